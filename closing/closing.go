@@ -0,0 +1,97 @@
+// Package closing closes issues referred to by closing keywords,
+// like "Fixes #12" or "Closes owner/repo#3", in commit and change descriptions.
+package closing
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shurcooL/issues"
+)
+
+// Ref is a reference to an issue that a message closes.
+type Ref struct {
+	Repo issues.RepoSpec
+	ID   uint64
+}
+
+// keywordRE matches a closing keyword followed by one or more issue references,
+// like "Fixes #1, #2 and owner/repo#3".
+var keywordRE = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?[ \t]+((?:(?:[A-Za-z0-9_.\-/]+)?#[0-9]+(?:[ \t]*(?:,|,?[ \t]+and)[ \t]*)?)+)`)
+
+// refRE matches a single issue reference within a match of keywordRE.
+var refRE = regexp.MustCompile(`([A-Za-z0-9_.\-/]+)?#([0-9]+)`)
+
+// Parse returns unique issues that msg closes, in order of appearance.
+// References without a repository, like "#12", refer to an issue in repo.
+// Other references are either a full repository URI, like "example.com/foo#12",
+// when their first path element contains a dot, or else a path relative to
+// the host of repo, like "owner/other#12" from "github.com/owner/repo".
+func Parse(repo issues.RepoSpec, msg string) []Ref {
+	var (
+		refs []Ref
+		seen = make(map[Ref]bool)
+	)
+	for _, m := range keywordRE.FindAllStringSubmatch(msg, -1) {
+		for _, r := range refRE.FindAllStringSubmatch(m[1], -1) {
+			id, err := strconv.ParseUint(r[2], 10, 64)
+			if err != nil || id == 0 {
+				continue
+			}
+			ref := Ref{Repo: resolve(repo, r[1]), ID: id}
+			if ref.Repo.URI == "" || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolve resolves the repository ref, as written in a reference made from repo.
+// It returns a zero RepoSpec if ref is invalid.
+func resolve(repo issues.RepoSpec, ref string) issues.RepoSpec {
+	ref = strings.Trim(ref, "/")
+	switch {
+	case ref == "":
+		return repo
+	case path.Clean("/"+ref) != "/"+ref:
+		return issues.RepoSpec{}
+	case strings.Contains(strings.SplitN(ref, "/", 2)[0], "."):
+		return issues.RepoSpec{URI: ref}
+	}
+	host := repo.URI
+	if i := strings.IndexByte(host, '/'); i != -1 {
+		host = host[:i]
+	}
+	return issues.RepoSpec{URI: path.Join(host, ref)}
+}
+
+// Apply closes the issues that msg closes on behalf of closer,
+// which must be an issues.Change or an issues.Commit.
+// Issues that are already closed are left as is.
+// It returns the first error encountered, after attempting to close all issues.
+//
+// service must implement issues.Closer, or Apply returns an error. Of the services
+// in this repository, only fs does. GitHub resolves closers itself, when commits and
+// pull requests that refer to issues with closing keywords are merged, so Apply isn't
+// needed with githubapi, and maintner reflects what GitHub did.
+func Apply(ctx context.Context, service issues.Service, repo issues.RepoSpec, msg string, closer interface{}) error {
+	c, ok := service.(issues.Closer)
+	if !ok {
+		return fmt.Errorf("service %T doesn't implement issues.Closer", service)
+	}
+	var firstErr error
+	for _, ref := range Parse(repo, msg) {
+		_, _, err := c.SetState(ctx, ref.Repo, ref.ID, issues.ClosedState, issues.Close{Closer: closer})
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("closing %s#%d: %v", ref.Repo.URI, ref.ID, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,85 @@
+package closing_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"dmitri.shuralyov.com/state"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/issues/closing"
+)
+
+func TestParse(t *testing.T) {
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+	msg := `fs: fix crash on startup
+
+This fixes #12, #13 and other/repo#3.
+It's related to #14, but doesn't fix it.
+
+Closes example.com/foo#7.
+Resolves: #12
+Fixed #0. Fixes issue #15.
+`
+	got := closing.Parse(repo, msg)
+	want := []closing.Ref{
+		{Repo: repo, ID: 12},
+		{Repo: repo, ID: 13},
+		{Repo: issues.RepoSpec{URI: "github.com/other/repo"}, ID: 3},
+		{Repo: issues.RepoSpec{URI: "example.com/foo"}, ID: 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	change := issues.Change{State: state.ChangeMerged, Title: "fs: fix crash", HTMLURL: "https://example.com/change/1"}
+
+	// Issues that msg closes are closed on behalf of change, in order,
+	// and only the first error is returned, after attempting all of them.
+	s := &mockCloser{fail: map[uint64]bool{1: true}}
+	err := closing.Apply(ctx, s, repo, "fs: fix crash\n\nFixes #1, #3 and other/repo#2. Updates #4.\n", change)
+	if want := "closing example.com/repo#1: failed"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+	want := []setStateCall{
+		{Repo: repo, ID: 1, State: issues.ClosedState, Close: issues.Close{Closer: change}},
+		{Repo: repo, ID: 3, State: issues.ClosedState, Close: issues.Close{Closer: change}},
+		{Repo: issues.RepoSpec{URI: "example.com/other/repo"}, ID: 2, State: issues.ClosedState, Close: issues.Close{Closer: change}},
+	}
+	if !reflect.DeepEqual(s.calls, want) {
+		t.Errorf("got SetState calls %+v, want %+v", s.calls, want)
+	}
+
+	// Services that don't implement issues.Closer are reported.
+	if err := closing.Apply(ctx, struct{ issues.Service }{s}, repo, "Fixes #2.", change); err == nil {
+		t.Error("Apply succeeded with a service that doesn't implement issues.Closer")
+	}
+}
+
+// mockCloser is an issues.Closer that records SetState calls,
+// and fails them for issues in fail.
+type mockCloser struct {
+	issues.Service
+	fail  map[uint64]bool
+	calls []setStateCall
+}
+
+type setStateCall struct {
+	Repo  issues.RepoSpec
+	ID    uint64
+	State issues.State
+	Close issues.Close
+}
+
+func (s *mockCloser) SetState(_ context.Context, repo issues.RepoSpec, id uint64, state issues.State, c issues.Close) (issues.Issue, []issues.Event, error) {
+	s.calls = append(s.calls, setStateCall{Repo: repo, ID: id, State: state, Close: c})
+	if s.fail[id] {
+		return issues.Issue{}, nil, errors.New("failed")
+	}
+	return issues.Issue{}, nil, nil
+}
@@ -0,0 +1,219 @@
+package issues_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/issues/fs"
+	"github.com/shurcooL/issues/githubapi"
+	"github.com/shurcooL/issues/maintner"
+	"github.com/shurcooL/issues/mbox"
+	"github.com/shurcooL/reactions"
+	"github.com/shurcooL/users"
+	xmaintner "golang.org/x/build/maintner"
+	"golang.org/x/build/maintner/maintpb"
+	"golang.org/x/net/webdav"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TestGetDescription tests that Get returns the same issue description
+// as the first comment listed by ListTimeline or ListComments, in all services.
+func TestGetDescription(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		new  func(t *testing.T) (context.Context, issues.Service, issues.RepoSpec)
+	}{
+		{"fs", newFS},
+		{"githubapi", newGitHubAPI},
+		{"maintner", newMaintner},
+		{"mbox", newMbox},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, s, repo := tc.new(t)
+			issue, err := s.Get(ctx, repo, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := description(ctx, s, repo, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := issue.Comment; !reflect.DeepEqual(got, want) {
+				t.Errorf("Get and the first listed comment disagree:\ngot  %+v\nwant %+v", got, want)
+			}
+			if issue.Body == "" {
+				t.Error("Get returned an empty body")
+			}
+		})
+	}
+}
+
+// description returns the first comment of the specified issue, as listed by s.
+func description(ctx context.Context, s issues.Service, repo issues.RepoSpec, id uint64) (issues.Comment, error) {
+	if tl, ok := s.(issues.TimelineLister); ok && tl.IsTimelineLister(repo) {
+		items, err := tl.ListTimeline(ctx, repo, id, &issues.ListOptions{Length: 1})
+		if err != nil {
+			return issues.Comment{}, err
+		}
+		if len(items) == 0 {
+			return issues.Comment{}, fmt.Errorf("empty timeline")
+		}
+		c, ok := items[0].(issues.Comment)
+		if !ok {
+			return issues.Comment{}, fmt.Errorf("first timeline item is %T, want issues.Comment", items[0])
+		}
+		return c, nil
+	}
+	cs, err := s.ListComments(ctx, repo, id, &issues.ListOptions{Length: 1})
+	if err != nil {
+		return issues.Comment{}, err
+	}
+	if len(cs) == 0 {
+		return issues.Comment{}, fmt.Errorf("no comments")
+	}
+	return cs[0], nil
+}
+
+func newFS(t *testing.T) (context.Context, issues.Service, issues.RepoSpec) {
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	alice := users.User{UserSpec: users.UserSpec{ID: 1, Domain: "example.com"}, Login: "alice"}
+	s, err := fs.NewService(webdav.NewMemFS(), nil, nil, signedIn(alice), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Title", Comment: issues.Comment{Body: "Body"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "Edited body"
+	reaction := reactions.EmojiID("+1")
+	_, err = s.EditComment(ctx, repo, 1, issues.CommentRequest{ID: 0, Body: &body, Reaction: &reaction})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx, s, repo
+}
+
+func newGitHubAPI(t *testing.T) (context.Context, issues.Service, issues.RepoSpec) {
+	const (
+		description = `"author":{"databaseId":1,"login":"gopher"},"publishedAt":"2018-01-01T00:00:00Z",` +
+			`"lastEditedAt":"2018-01-02T00:00:00Z","editor":{"databaseId":2,"login":"editor"},"body":"Body",` +
+			`"reactionGroups":[{"content":"THUMBS_UP","users":{"nodes":[{"databaseId":1,"login":"gopher"}],"totalCount":1},"viewerHasReacted":true}],` +
+			`"viewerCanUpdate":true`
+		viewer = `"viewer":{"databaseId":1,"login":"gopher"}`
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var data string
+		switch {
+		case strings.Contains(string(body), "projectItems"):
+			data = `{"repository":{"issue":{"projectItems":{"nodes":[]}}}}`
+		case strings.Contains(string(body), "timeline("):
+			data = `{"repository":{"issue":{` + description + `,"timeline":{"nodes":[],"pageInfo":{"endCursor":"","hasNextPage":false}}}},` + viewer + `}`
+		default:
+			data = `{"repository":{"issue":{"number":1,"state":"OPEN","title":"Title","labels":{"nodes":[]},"assignees":{"nodes":[]},"milestone":null,` +
+				description + `,"comments":{"totalCount":0}}},` + viewer + `}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data":`+data+`}`)
+	}))
+	t.Cleanup(srv.Close)
+	s := githubapi.NewService(githubv4.NewEnterpriseClient(srv.URL, srv.Client()), nil, nil)
+	return context.Background(), s, issues.RepoSpec{URI: "github.com/owner/repo"}
+}
+
+func newMaintner(t *testing.T) (context.Context, issues.Service, issues.RepoSpec) {
+	created := timestamppb.New(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	mutations := xmaintner.NewDiskMutationLogger(t.TempDir())
+	err := mutations.Log(&maintpb.Mutation{GithubIssue: &maintpb.GithubIssueMutation{
+		Owner:   "owner",
+		Repo:    "repo",
+		Number:  1,
+		Id:      1001,
+		User:    &maintpb.GithubUser{Id: 1, Login: "gopher"},
+		Created: created,
+		Updated: created,
+		Title:   "Title",
+		Body:    "Body",
+		Comment: []*maintpb.GithubIssueCommentMutation{{
+			Id:      2001,
+			User:    &maintpb.GithubUser{Id: 2, Login: "reviewer"},
+			Body:    "Comment",
+			Created: created,
+			Updated: created,
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	corpus := new(xmaintner.Corpus)
+	if err := corpus.Initialize(context.Background(), mutations); err != nil {
+		t.Fatal(err)
+	}
+	return context.Background(), maintner.NewService(corpus, nil), issues.RepoSpec{URI: "owner/repo"}
+}
+
+func newMbox(t *testing.T) (context.Context, issues.Service, issues.RepoSpec) {
+	const archive = `From alice@example.com Mon Jan  2 15:04:05 2017
+From: Alice <alice@example.com>
+Date: Mon, 02 Jan 2017 15:04:05 +0000
+Subject: Crash on startup
+Message-ID: <abc@example.com>
+
+It crashes.
+
+From bob@example.com Mon Jan  2 16:00:00 2017
+From: Bob <bob@example.com>
+Date: Mon, 02 Jan 2017 16:00:00 +0000
+Subject: Re: Crash on startup
+Message-ID: <def@example.com>
+In-Reply-To: <abc@example.com>
+
+Fixed, thanks!
+
+`
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	s, err := mbox.Import(strings.NewReader(archive), repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return context.Background(), s, repo
+}
+
+// signedIn is a users.Service where user u is always authenticated,
+// and is the only user that exists.
+type signedIn users.User
+
+func (u signedIn) Get(_ context.Context, user users.UserSpec) (users.User, error) {
+	if user != u.UserSpec {
+		return users.User{}, os.ErrNotExist
+	}
+	return users.User(u), nil
+}
+
+func (u signedIn) GetAuthenticatedSpec(context.Context) (users.UserSpec, error) {
+	return u.UserSpec, nil
+}
+
+func (u signedIn) GetAuthenticated(context.Context) (users.User, error) {
+	return users.User(u), nil
+}
+
+func (signedIn) Edit(context.Context, users.EditRequest) (users.User, error) {
+	return users.User{}, fmt.Errorf("Edit: not implemented")
+}
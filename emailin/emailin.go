@@ -0,0 +1,351 @@
+// Package emailin creates issues and comments from inbound email messages.
+//
+// It's meant to be used by a mail server that receives replies to notification
+// emails. The target issue is resolved either from a signed token in the reply
+// address (see Handler.ReplyAddress), or from the In-Reply-To and References
+// headers, if they refer to a message ID created by package mbox and the caller
+// verified the sender (see Handler.VerifySender).
+package emailin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/issues/mbox"
+	"github.com/shurcooL/users"
+)
+
+// Handler handles inbound email messages by creating issues and comments.
+type Handler struct {
+	// Issues is the service where issues and comments are created.
+	Issues issues.Service
+
+	// Users is used to authenticate senders. The email address of the user
+	// resolved for a message must match the message's From address.
+	Users users.Service
+
+	// Secret is the key used to sign and verify reply address tokens.
+	Secret []byte
+
+	// Domain is the domain of reply addresses created by ReplyAddress.
+	Domain string
+
+	// Targets stores the targets of reply addresses created by ReplyAddress.
+	Targets TargetStore
+
+	// TokenTTL is how long reply addresses created by ReplyAddress are valid.
+	// If zero, DefaultTokenTTL is used.
+	TokenTTL time.Duration
+
+	// UserByEmail, if not nil, looks up the user with the given email address.
+	// It's used to identify senders of messages that don't have a reply address token,
+	// but refer to an issue via their In-Reply-To or References headers.
+	// Such messages are rejected if UserByEmail or VerifySender is nil.
+	UserByEmail func(ctx context.Context, address string) (users.UserSpec, error)
+
+	// VerifySender reports whether the From address of a message with header
+	// was verified by the caller, for example, because the receiving MTA found that
+	// the message passed DKIM or SPF checks for a domain aligned with the From address.
+	// The From header alone is controlled by the sender, so it can't be trusted.
+	// It's only used for messages without a reply address token.
+	VerifySender func(ctx context.Context, header mail.Header, from *mail.Address) bool
+
+	// WithUser, if not nil, returns a context authenticated as user.
+	// It's used for calls to Issues, so that issues and comments are created by the sender.
+	WithUser func(ctx context.Context, user users.UserSpec) context.Context
+}
+
+// DefaultTokenTTL is how long reply addresses are valid if Handler.TokenTTL is zero.
+const DefaultTokenTTL = 30 * 24 * time.Hour
+
+// ReplyAddress returns an email address that lets user comment on the specified issue
+// by sending email to it. If issueID is 0, messages sent to the address create new issues in repo.
+// The address expires after TokenTTL.
+func (h *Handler) ReplyAddress(ctx context.Context, repo issues.RepoSpec, issueID uint64, user users.UserSpec) (string, error) {
+	t := Target{Repo: repo, IssueID: issueID, User: user}
+	key := h.key(t)
+	err := h.Targets.PutTarget(ctx, key, t)
+	if err != nil {
+		return "", err
+	}
+	ttl := h.TokenTTL
+	if ttl == 0 {
+		ttl = DefaultTokenTTL
+	}
+	return "reply+" + h.token(key, timeNow().Add(ttl)) + "@" + h.Domain, nil
+}
+
+// Handle reads an RFC 5322 message from r, and creates an issue or a comment from it.
+// It returns os.ErrPermission if the sender cannot be authenticated.
+func (h *Handler) Handle(ctx context.Context, r io.Reader) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return fmt.Errorf("invalid From header: %v", err)
+	}
+
+	t, err := h.target(ctx, msg.Header, from)
+	if err != nil {
+		return err
+	}
+
+	// Authenticate the sender.
+	user, err := h.Users.Get(ctx, t.User)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" || !strings.EqualFold(user.Email, from.Address) {
+		return os.ErrPermission
+	}
+	if h.WithUser != nil {
+		ctx = h.WithUser(ctx, t.User)
+	}
+
+	body, err := mbox.TextBody(msg)
+	if err != nil {
+		return err
+	}
+	body = StripQuotedReply(body)
+
+	if t.IssueID == 0 {
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			return err
+		}
+		_, err = h.Issues.Create(ctx, t.Repo, issues.Issue{
+			Title:   strings.TrimSpace(replyPrefix.ReplaceAllString(subject, "")),
+			Comment: issues.Comment{Body: body},
+		})
+		return err
+	}
+	_, err = h.Issues.CreateComment(ctx, t.Repo, t.IssueID, issues.Comment{Body: body})
+	return err
+}
+
+// Target is the issue that a message is for, and its sender.
+type Target struct {
+	Repo    issues.RepoSpec
+	IssueID uint64 // 0 means a new issue.
+	User    users.UserSpec
+}
+
+// TargetStore stores targets of reply addresses by opaque keys,
+// which keeps reply addresses short. It must persist targets
+// for as long as reply addresses are valid.
+type TargetStore interface {
+	// PutTarget stores t under key. The same key is always used for the same target.
+	PutTarget(ctx context.Context, key string, t Target) error
+	// GetTarget returns the target stored under key.
+	// It returns os.ErrNotExist if there's no such target.
+	GetTarget(ctx context.Context, key string) (Target, error)
+}
+
+// MemTargetStore is a TargetStore that keeps targets in memory.
+// Reply addresses stop working when it's discarded, such as on restart.
+type MemTargetStore struct {
+	mu      sync.Mutex
+	targets map[string]Target
+}
+
+// PutTarget implements TargetStore.
+func (s *MemTargetStore) PutTarget(_ context.Context, key string, t Target) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.targets == nil {
+		s.targets = make(map[string]Target)
+	}
+	s.targets[key] = t
+	return nil
+}
+
+// GetTarget implements TargetStore.
+func (s *MemTargetStore) GetTarget(_ context.Context, key string) (Target, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.targets[key]
+	if !ok {
+		return Target{}, os.ErrNotExist
+	}
+	return t, nil
+}
+
+// target resolves the target of a message with header h, sent from address from.
+// Reply address tokens take precedence over In-Reply-To and References headers,
+// which are only used if the sender was verified.
+func (h *Handler) target(ctx context.Context, header mail.Header, from *mail.Address) (Target, error) {
+	for _, key := range []string{"To", "Cc", "Delivered-To"} {
+		addrs, err := header.AddressList(key)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			local := addr.Address
+			if i := strings.LastIndex(local, "@"); i != -1 {
+				local = local[:i]
+			}
+			if i := strings.LastIndex(local, "+"); i != -1 {
+				local = local[i+1:]
+			}
+			key, ok := h.parseToken(local)
+			if !ok {
+				continue
+			}
+			t, err := h.Targets.GetTarget(ctx, key)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return Target{}, err
+			}
+			return t, nil
+		}
+	}
+
+	if h.UserByEmail == nil || h.VerifySender == nil || !h.VerifySender(ctx, header, from) {
+		return Target{}, os.ErrPermission
+	}
+	for _, id := range append(strings.Fields(header.Get("In-Reply-To")), strings.Fields(header.Get("References"))...) {
+		repo, issueID, ok := mbox.ParseMessageID(id)
+		if !ok {
+			continue
+		}
+		user, err := h.UserByEmail(ctx, from.Address)
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Repo: repo, IssueID: issueID, User: user}, nil
+	}
+	return Target{}, fmt.Errorf("message doesn't refer to an issue")
+}
+
+// tokenEncoding is used for tokens, since the local part of email addresses
+// is often treated as case-insensitive.
+var tokenEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+const (
+	keySize = 8  // keySize is the number of bytes of target keys.
+	macSize = 10 // macSize is the number of bytes of the HMAC-SHA256 kept in tokens.
+)
+
+// timeNow is time.Now, replaced in tests.
+var timeNow = time.Now
+
+// key returns the key that target t is stored under.
+// It's derived from t, so that the same target is stored once.
+func (h *Handler) key(t Target) string {
+	payload := strings.Join([]string{
+		"key",
+		t.Repo.URI,
+		strconv.FormatUint(t.IssueID, 10),
+		strconv.FormatUint(t.User.ID, 10),
+		t.User.Domain,
+	}, "\x00")
+	return tokenEncoding.EncodeToString(h.mac([]byte(payload))[:keySize])
+}
+
+// token returns a signed token that encodes a target key, and the time it expires.
+// It's 36 characters long, which keeps reply addresses within the 64 character
+// limit of the local part of email addresses.
+func (h *Handler) token(key string, expires time.Time) string {
+	k, err := tokenEncoding.DecodeString(key)
+	if err != nil || len(k) != keySize {
+		panic("invalid key") // Keys are created by h.key.
+	}
+	payload := make([]byte, keySize+4, keySize+4+macSize)
+	copy(payload, k)
+	binary.BigEndian.PutUint32(payload[keySize:], uint32(expires.Unix()))
+	return tokenEncoding.EncodeToString(append(payload, h.mac(payload)[:macSize]...))
+}
+
+// parseToken verifies a token created by token, and returns its target key.
+// Expired tokens aren't valid.
+func (h *Handler) parseToken(token string) (key string, ok bool) {
+	b, err := tokenEncoding.DecodeString(strings.ToLower(token))
+	if err != nil || len(b) != keySize+4+macSize {
+		return "", false
+	}
+	payload, mac := b[:keySize+4], b[keySize+4:]
+	if !hmac.Equal(mac, h.mac(payload)[:macSize]) {
+		return "", false
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint32(payload[keySize:])), 0)
+	if timeNow().After(expires) {
+		return "", false
+	}
+	return tokenEncoding.EncodeToString(payload[:keySize]), true
+}
+
+func (h *Handler) mac(payload []byte) []byte {
+	m := hmac.New(sha256.New, h.Secret)
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+var replyPrefix = regexp.MustCompile(`(?i)^\s*((re|fwd?):\s*)+`)
+
+// attribution matches lines like "On Mon, Jan 2, 2017 at 3:04 PM, Alice <alice@example.com> wrote:",
+// which mail clients insert above quoted text.
+var attribution = regexp.MustCompile(`^(On\s.+\swrote:|-+\s*Original Message\s*-+)\s*$`)
+
+// StripQuotedReply removes the quoted message that mail clients
+// append below a reply, along with its attribution line and the
+// sender's signature. Quotes interleaved with the reply are kept.
+func StripQuotedReply(body string) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+
+	// Cut at the signature separator.
+	for i, line := range lines {
+		if line == "-- " {
+			lines = lines[:i]
+			break
+		}
+	}
+	// Cut at an "Original Message" separator, which has unquoted text below it.
+	for i, line := range lines {
+		if attribution.MatchString(line) && !strings.HasPrefix(line, "On") {
+			lines = lines[:i]
+			break
+		}
+	}
+	// Cut the trailing quoted block.
+	end := len(lines)
+	for end > 0 && (strings.HasPrefix(lines[end-1], ">") || strings.TrimSpace(lines[end-1]) == "") {
+		end--
+	}
+	if end < len(lines) && hasQuote(lines[end:]) {
+		lines = lines[:end]
+		// Cut its attribution line, which may be wrapped over two lines.
+		switch n := len(lines); {
+		case n >= 1 && attribution.MatchString(lines[n-1]):
+			lines = lines[:n-1]
+		case n >= 2 && attribution.MatchString(lines[n-2]+" "+lines[n-1]):
+			lines = lines[:n-2]
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func hasQuote(lines []string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, ">") {
+			return true
+		}
+	}
+	return false
+}
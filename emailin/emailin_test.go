@@ -0,0 +1,219 @@
+package emailin_test
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/issues/emailin"
+	"github.com/shurcooL/issues/fs"
+	"github.com/shurcooL/issues/mbox"
+	"github.com/shurcooL/users"
+	"golang.org/x/net/webdav"
+)
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	alice := users.User{UserSpec: users.UserSpec{ID: 1, Domain: "example.com"}, Login: "alice", Email: "alice@example.com"}
+	us := mockUsers{alice.UserSpec: alice}
+	service, err := fs.NewService(webdav.NewMemFS(), nil, nil, us, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &emailin.Handler{
+		Issues:  service,
+		Users:   us,
+		Secret:  []byte("secret"),
+		Domain:  "issues.example.com",
+		Targets: new(emailin.MemTargetStore),
+		UserByEmail: func(_ context.Context, address string) (users.UserSpec, error) {
+			for spec, u := range us {
+				if u.Email == address {
+					return spec, nil
+				}
+			}
+			return users.UserSpec{}, os.ErrNotExist
+		},
+		VerifySender: func(_ context.Context, header mail.Header, _ *mail.Address) bool {
+			return strings.Contains(header.Get("Authentication-Results"), "dkim=pass")
+		},
+		WithUser: withUser,
+	}
+	replyAddress := func(h *emailin.Handler, issueID uint64) string {
+		addr, err := h.ReplyAddress(ctx, repo, issueID, alice.UserSpec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if local := addr[:strings.Index(addr, "@")]; len(local) > 64 {
+			t.Errorf("reply address local part %q is %d characters long, want at most 64", local, len(local))
+		}
+		return addr
+	}
+
+	// Create a new issue via a reply address for repo.
+	newIssue := fmt.Sprintf(`From: Alice <alice@example.com>
+To: %s
+Subject: Crash on startup
+Content-Type: multipart/alternative; boundary="b"
+
+--b
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+It crashes on startup =E2=80=94 every time.
+
+--=20
+Alice
+--b
+Content-Type: text/html; charset=utf-8
+
+<p>It crashes on startup &mdash; every time.</p>
+--b--
+`, replyAddress(h, 0))
+	err = h.Handle(ctx, strings.NewReader(newIssue))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reply to a notification email via In-Reply-To. It's only accepted
+	// if the sender is verified, since the From header can be forged.
+	reply := fmt.Sprintf(`From: alice@example.com
+To: issues@issues.example.com
+Subject: Re: Crash on startup
+In-Reply-To: <%s>
+
+Unverified.
+`, mbox.CommentMessageID(repo, 1, 0))
+	if err := h.Handle(ctx, strings.NewReader(reply)); err != os.ErrPermission {
+		t.Errorf("got error %v, want %v", err, os.ErrPermission)
+	}
+	reply = fmt.Sprintf(`From: alice@example.com
+To: issues@issues.example.com
+Authentication-Results: mx.example.com; dkim=pass header.d=example.com
+Subject: Re: Crash on startup
+In-Reply-To: <%s>
+
+Here's a stack trace.
+
+On Mon, Jan 2, 2017 at 3:04 PM, Alice <alice@example.com>
+wrote:
+> It crashes on startup.
+`, mbox.CommentMessageID(repo, 1, 0))
+	err = h.Handle(ctx, strings.NewReader(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages from other senders are rejected, even with a valid reply address.
+	forged := fmt.Sprintf(`From: Mallory <mallory@example.com>
+To: %s
+Subject: Re: Crash on startup
+
+Spam.
+`, replyAddress(h, 1))
+	if err := h.Handle(ctx, strings.NewReader(forged)); err != os.ErrPermission {
+		t.Errorf("got error %v, want %v", err, os.ErrPermission)
+	}
+
+	// Expired reply addresses are rejected.
+	expired := *h
+	expired.TokenTTL = -time.Minute
+	late := fmt.Sprintf(`From: Alice <alice@example.com>
+To: %s
+Subject: Re: Crash on startup
+
+Late.
+`, replyAddress(&expired, 1))
+	if err := h.Handle(ctx, strings.NewReader(late)); err != os.ErrPermission {
+		t.Errorf("got error %v, want %v", err, os.ErrPermission)
+	}
+
+	ctx = withUser(ctx, alice.UserSpec)
+	issue, err := service.Get(ctx, repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := issue.Title, "Crash on startup"; got != want {
+		t.Errorf("got title %q, want %q", got, want)
+	}
+	comments, err := service.ListComments(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range comments {
+		if c.User.UserSpec != alice.UserSpec {
+			t.Errorf("comment %d author is %v, want %v", c.ID, c.User.UserSpec, alice.UserSpec)
+		}
+		got = append(got, c.Body)
+	}
+	want := []string{"It crashes on startup — every time.", "Here's a stack trace."}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got comments %q, want %q", got, want)
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "Sounds good.\n\nOn Mon, Jan 2, 2017, Bob <bob@example.com> wrote:\n> Shall we?\n>\n> Bob\n",
+			want: "Sounds good.",
+		},
+		{
+			in:   "> Shall we?\n\nYes.\n\n> And then?\n\nNo.\n",
+			want: "> Shall we?\n\nYes.\n\n> And then?\n\nNo.",
+		},
+		{
+			in:   "Sure.\n\n-----Original Message-----\nFrom: Bob\n\nShall we?\n",
+			want: "Sure.",
+		},
+	}
+	for _, tc := range tests {
+		if got := emailin.StripQuotedReply(tc.in); got != tc.want {
+			t.Errorf("StripQuotedReply(%q):\ngot  %q\nwant %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+type contextKey struct{}
+
+func withUser(ctx context.Context, user users.UserSpec) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// mockUsers is a users.Service that authenticates the user set by withUser.
+type mockUsers map[users.UserSpec]users.User
+
+func (us mockUsers) Get(_ context.Context, user users.UserSpec) (users.User, error) {
+	u, ok := us[user]
+	if !ok {
+		return users.User{}, os.ErrNotExist
+	}
+	return u, nil
+}
+
+func (us mockUsers) GetAuthenticatedSpec(ctx context.Context) (users.UserSpec, error) {
+	spec, _ := ctx.Value(contextKey{}).(users.UserSpec)
+	return spec, nil
+}
+
+func (us mockUsers) GetAuthenticated(ctx context.Context) (users.User, error) {
+	spec, _ := us.GetAuthenticatedSpec(ctx)
+	if spec.ID == 0 {
+		return users.User{}, nil
+	}
+	return us.Get(ctx, spec)
+}
+
+func (mockUsers) Edit(context.Context, users.EditRequest) (users.User, error) {
+	return users.User{}, fmt.Errorf("Edit: not implemented")
+}
@@ -17,6 +17,10 @@ type Event struct {
 	Rename    *Rename    // Rename is only provided for Renamed events.
 	Label     *Label     // Label is only provided for Labeled and Unlabeled events.
 	Milestone *Milestone // Milestone is only provided for Milestoned and Demilestoned events.
+
+	CrossReference *CrossReference // CrossReference is only provided for CrossReferenced events.
+	Relation       *Relation       // Relation is only provided for Related and Unrelated events.
+	FieldChange    *FieldChange    // FieldChange is only provided for FieldChanged events.
 }
 
 // EventType is the type of an event.
@@ -39,12 +43,20 @@ const (
 	Demilestoned EventType = "demilestoned"
 	// CommentDeleted is when an issue comment is deleted.
 	CommentDeleted EventType = "comment_deleted"
+	// CrossReferenced is when an issue is referenced from another issue, a commit, or a change.
+	CrossReferenced EventType = "cross_referenced"
+	// Related is when a relation to another issue is added.
+	Related EventType = "related"
+	// Unrelated is when a relation to another issue is removed.
+	Unrelated EventType = "unrelated"
+	// FieldChanged is when the value of a custom field of an issue is changed.
+	FieldChanged EventType = "field_changed"
 )
 
 // Valid returns non-nil error if the event type is invalid.
 func (et EventType) Valid() bool {
 	switch et {
-	case Reopened, Closed, Renamed, Labeled, Unlabeled, Milestoned, Demilestoned, CommentDeleted:
+	case Reopened, Closed, Renamed, Labeled, Unlabeled, Milestoned, Demilestoned, CommentDeleted, CrossReferenced, Related, Unrelated, FieldChanged:
 		return true
 	default:
 		return false
@@ -53,7 +65,7 @@ func (et EventType) Valid() bool {
 
 // Close provides details for a Closed event.
 type Close struct {
-	Closer interface{} // Change, Commit, nil.
+	Closer interface{} // Change, Commit, IssueRef, nil.
 }
 
 // Change describes a change that closed an issue.
@@ -71,6 +83,20 @@ type Commit struct {
 	HTMLURL         string
 }
 
+// CrossReference provides details for a CrossReferenced event.
+type CrossReference struct {
+	Source interface{} // IssueRef, Commit, Change.
+}
+
+// IssueRef describes an issue that referenced another issue,
+// or that an issue was closed as a duplicate of.
+type IssueRef struct {
+	Repo    RepoSpec
+	ID      uint64
+	Title   string
+	HTMLURL string // Address of the comment with the reference, or of the issue.
+}
+
 // Rename provides details for a Renamed event.
 type Rename struct {
 	From string
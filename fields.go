@@ -0,0 +1,179 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/users"
+)
+
+// FieldLister is an optional interface that lists definitions of custom fields of issues in a repo.
+// Values of custom fields are provided in Issue.Fields, and can be set via IssueRequest.Fields
+// (unless the field is read-only) and when creating an issue.
+type FieldLister interface {
+	// ListFields lists custom field definitions of the specified repo.
+	ListFields(ctx context.Context, repo RepoSpec) ([]FieldDef, error)
+}
+
+// FieldDef is a definition of a custom field, like priority or estimate.
+type FieldDef struct {
+	Name     string
+	Type     FieldType
+	Options  []string // Options are allowed values of an EnumField, in order.
+	ReadOnly bool     // ReadOnly fields can't be set via this API.
+}
+
+// FieldType is the type of a custom field.
+type FieldType string
+
+const (
+	// EnumField is a field whose value is one of its definition's options. Its values are strings.
+	EnumField FieldType = "enum"
+	// IntField is an integer field. Its values are int64s.
+	IntField FieldType = "int"
+	// StringField is a free-form text field. Its values are strings.
+	StringField FieldType = "string"
+	// DateField is a date field. Its values are time.Times at midnight UTC.
+	DateField FieldType = "date"
+	// UserField is a field whose value is a user. Its values are users.UserSpecs.
+	UserField FieldType = "user"
+)
+
+// Field is the value of a custom field of an issue.
+type Field struct {
+	Name  string
+	Value interface{} // string, int64, time.Time, users.UserSpec, or nil if unset.
+}
+
+// FieldChange provides details for a FieldChanged event.
+type FieldChange struct {
+	Name string
+	From interface{} // From is the previous value, or nil if it was unset.
+	To   interface{} // To is the new value, or nil if it's been unset.
+}
+
+// Validate returns non-nil error if v isn't a valid value for field d.
+// A nil v, which unsets the field, is always valid.
+func (d FieldDef) Validate(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	ok := false
+	switch d.Type {
+	case EnumField:
+		s, isString := v.(string)
+		for _, o := range d.Options {
+			ok = ok || (isString && s == o)
+		}
+		if !ok {
+			return &InvalidArgumentError{Field: d.Name, Reason: fmt.Sprintf("%v is not one of %q", v, d.Options)}
+		}
+		return nil
+	case IntField:
+		_, ok = v.(int64)
+	case StringField:
+		_, ok = v.(string)
+	case DateField:
+		var t time.Time
+		t, ok = v.(time.Time)
+		if ok && !t.Equal(t.UTC().Truncate(24*time.Hour)) {
+			return &InvalidArgumentError{Field: d.Name, Reason: "date must be at midnight UTC"}
+		}
+	case UserField:
+		_, ok = v.(users.UserSpec)
+	default:
+		return &InvalidArgumentError{Field: d.Name, Reason: fmt.Sprintf("unsupported field type %q", d.Type)}
+	}
+	if !ok {
+		return &InvalidArgumentError{Field: d.Name, Reason: fmt.Sprintf("value of type %T is not valid for a %s field", v, d.Type)}
+	}
+	return nil
+}
+
+// Compare compares values a and b of field d, returning -1, 0 or +1.
+// Enum values are ordered by their position in d.Options.
+// Unset values (nil) order after all set values.
+func (d FieldDef) Compare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return +1
+	case b == nil:
+		return -1
+	}
+	switch a := a.(type) {
+	case string:
+		b, _ := b.(string)
+		if d.Type == EnumField {
+			return compareInt(int64(indexOf(d.Options, a)), int64(indexOf(d.Options, b)))
+		}
+		return strings.Compare(a, b)
+	case int64:
+		b, _ := b.(int64)
+		return compareInt(a, b)
+	case time.Time:
+		b, _ := b.(time.Time)
+		switch {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return +1
+		}
+		return 0
+	case users.UserSpec:
+		b, _ := b.(users.UserSpec)
+		if c := strings.Compare(a.Domain, b.Domain); c != 0 {
+			return c
+		}
+		return compareInt(int64(a.ID), int64(b.ID))
+	}
+	return 0
+}
+
+// FieldValues returns values of fields in fs by name.
+func FieldValues(fs []Field) map[string]interface{} {
+	m := make(map[string]interface{}, len(fs))
+	for _, f := range fs {
+		if f.Value != nil {
+			m[f.Name] = f.Value
+		}
+	}
+	return m
+}
+
+// validateFieldNames returns non-nil error if fields have blank or duplicate names.
+func validateFieldNames(fields []Field) error {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if strings.TrimSpace(f.Name) == "" {
+			return fmt.Errorf("field name can't be blank or all whitespace")
+		}
+		if seen[f.Name] {
+			return &InvalidArgumentError{Field: f.Name, Reason: "field is specified more than once"}
+		}
+		seen[f.Name] = true
+	}
+	return nil
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return +1
+	}
+	return 0
+}
+
+func indexOf(ss []string, s string) int {
+	for i, v := range ss {
+		if v == s {
+			return i
+		}
+	}
+	return len(ss)
+}
@@ -28,19 +28,17 @@ func (s *service) CopyFrom(ctx context.Context, src issues.Service, repo issues.
 			return err
 		}
 		// Copy issue.
+		var fields []field
+		for _, f := range i.Fields {
+			if f.Value != nil {
+				fields = append(fields, fromField(f))
+			}
+		}
 		issue := issue{
-			State: i.State,
-			Title: i.Title,
-			comment: comment{
-				Author:    fromUserSpec(i.User.UserSpec),
-				CreatedAt: i.CreatedAt,
-				// TODO: This doesn't work, Get doesn't return body, reactions, etc.; using ListComments for now for that... Improve this.
-				//       Perhaps this is motivation to separate Comment out of Issue, so get can return only Issue and it's clear that Comment details are elsewhere.
-				//       Just leave non-comment-specific things in Issue like Author and CreatedAt, but remove Body, Reactions, etc., those belong to comment only.
-				//       That would also make the distinction between reactions to first issue comment (its body) and reactions to entire issue (i.e. in list view), if that's ever desireable.
-				//       However, it would just mean that Create endpoint would likely need to create an issue and then a comment (2 storage ops rater than 1), but that's completely fair.
-				//Body:      i.Body,
-			},
+			State:   i.State,
+			Title:   i.Title,
+			Fields:  fields,
+			comment: copyComment(i.Comment),
 		}
 
 		// Put in storage.
@@ -52,7 +50,10 @@ func (s *service) CopyFrom(ctx context.Context, src issues.Service, repo issues.
 		if err != nil {
 			return err
 		}
-		// Issue will be created as part of first comment, since we need to embed its comment too.
+		err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, i.ID, 0), issue)
+		if err != nil {
+			return err
+		}
 
 		comments, err := src.ListComments(ctx, repo, i.ID, nil)
 		if err != nil {
@@ -60,35 +61,13 @@ func (s *service) CopyFrom(ctx context.Context, src issues.Service, repo issues.
 		}
 		fmt.Printf("Issue %v: Copying %v comments.\n", i.ID, len(comments))
 		for _, c := range comments {
-			// Copy comment.
-			comment := comment{
-				Author:    fromUserSpec(c.User.UserSpec),
-				CreatedAt: c.CreatedAt,
-				Body:      c.Body,
-			}
-			for _, r := range c.Reactions {
-				reaction := reaction{
-					EmojiID: r.Reaction,
-				}
-				for _, u := range r.Users {
-					reaction.Authors = append(reaction.Authors, fromUserSpec(u.UserSpec))
-				}
-				comment.Reactions = append(comment.Reactions, reaction)
-			}
-
 			if c.ID == 0 {
-				issue.comment = comment
-
-				// Put in storage.
-				err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, i.ID, 0), issue)
-				if err != nil {
-					return err
-				}
+				// The issue description was copied with the issue.
 				continue
 			}
 
 			// Put in storage.
-			err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, i.ID, c.ID), comment)
+			err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, i.ID, c.ID), copyComment(c))
 			if err != nil {
 				return err
 			}
@@ -106,6 +85,23 @@ func (s *service) CopyFrom(ctx context.Context, src issues.Service, repo issues.
 				CreatedAt: e.CreatedAt,
 				Type:      e.Type,
 				Rename:    e.Rename,
+
+				CrossReference: fromCrossReference(e.CrossReference),
+			}
+			if c := e.FieldChange; c != nil {
+				event.FieldChange = &fieldChange{Name: c.Name}
+				if c.From != nil {
+					from := fromField(issues.Field{Name: c.Name, Value: c.From})
+					event.FieldChange.From = &from
+				}
+				if c.To != nil {
+					to := fromField(issues.Field{Name: c.Name, Value: c.To})
+					event.FieldChange.To = &to
+				}
+			}
+			if e.Relation != nil {
+				r := fromRelation(*e.Relation)
+				event.Relation = &r
 			}
 
 			// Put in storage.
@@ -119,3 +115,28 @@ func (s *service) CopyFrom(ctx context.Context, src issues.Service, repo issues.
 	fmt.Println("All done.")
 	return nil
 }
+
+// copyComment converts c into an on-disk comment.
+func copyComment(c issues.Comment) comment {
+	comment := comment{
+		Author:    fromUserSpec(c.User.UserSpec),
+		CreatedAt: c.CreatedAt,
+		Body:      c.Body,
+	}
+	if c.Edited != nil {
+		comment.Edited = &edited{
+			By: fromUserSpec(c.Edited.By.UserSpec),
+			At: c.Edited.At,
+		}
+	}
+	for _, r := range c.Reactions {
+		reaction := reaction{
+			EmojiID: r.Reaction,
+		}
+		for _, u := range r.Users {
+			reaction.Authors = append(reaction.Authors, fromUserSpec(u.UserSpec))
+		}
+		comment.Reactions = append(comment.Reactions, reaction)
+	}
+	return comment
+}
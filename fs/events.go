@@ -10,7 +10,7 @@ import (
 	"github.com/shurcooL/users"
 )
 
-func (s *service) logIssue(ctx context.Context, repo issues.RepoSpec, issueID uint64, fragment string, issue issue, actor users.User, action string, time time.Time) error {
+func (s *service) logIssue(ctx context.Context, repo issues.RepoSpec, issueID uint64, htmlURL string, issue issue, actor users.User, action string, time time.Time) error {
 	if s.events == nil {
 		return nil
 	}
@@ -24,13 +24,13 @@ func (s *service) logIssue(ctx context.Context, repo issues.RepoSpec, issueID ui
 			Action:       action,
 			IssueTitle:   issue.Title,
 			IssueBody:    issue.Body,
-			IssueHTMLURL: htmlURL(repo.URI, issueID, fragment),
+			IssueHTMLURL: htmlURL,
 		},
 	}
 	return s.events.Log(ctx, event)
 }
 
-func (s *service) logIssueComment(ctx context.Context, repo issues.RepoSpec, issueID uint64, fragment string, actor users.User, time time.Time, body string) error {
+func (s *service) logIssueComment(ctx context.Context, repo issues.RepoSpec, issueID uint64, htmlURL string, actor users.User, time time.Time, body string) error {
 	if s.events == nil {
 		return nil
 	}
@@ -52,7 +52,35 @@ func (s *service) logIssueComment(ctx context.Context, repo issues.RepoSpec, iss
 			IssueTitle:     issue.Title,
 			IssueState:     state.Issue(issue.State), // TODO: Make the conversion go away (by making issues.State type state.Issue).
 			CommentBody:    body,
-			CommentHTMLURL: htmlURL(repo.URI, issueID, fragment),
+			CommentHTMLURL: htmlURL,
+		},
+	}
+	return s.events.Log(ctx, event)
+}
+
+// logIssueAction logs an action on an issue that doesn't change its state,
+// such as editing or reacting to a comment at htmlURL.
+func (s *service) logIssueAction(ctx context.Context, repo issues.RepoSpec, issueID uint64, htmlURL string, actor users.User, action string, time time.Time) error {
+	if s.events == nil {
+		return nil
+	}
+
+	// Get issue from storage for to populate event fields.
+	var issue issue
+	err := jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, issueID, 0), &issue)
+	if err != nil {
+		return err
+	}
+
+	event := eventpkg.Event{
+		Time:      time,
+		Actor:     actor,
+		Container: repo.URI,
+
+		Payload: eventpkg.Issue{
+			Action:       action,
+			IssueTitle:   issue.Title,
+			IssueHTMLURL: htmlURL,
 		},
 	}
 	return s.events.Log(ctx, event)
@@ -0,0 +1,181 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/shurcooL/issues"
+)
+
+// ListFields implements issues.FieldLister.
+func (s *service) ListFields(ctx context.Context, repo issues.RepoSpec) ([]issues.FieldDef, error) {
+	s.fsMu.RLock()
+	defer s.fsMu.RUnlock()
+
+	return s.fieldDefs(ctx, repo)
+}
+
+// fieldDefs reads custom field definitions of repo.
+// It returns no definitions if repo has none.
+func (s *service) fieldDefs(ctx context.Context, repo issues.RepoSpec) ([]issues.FieldDef, error) {
+	var ds []fieldDef
+	err := jsonDecodeFile(ctx, s.fs, fieldDefsPath(repo), &ds)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var defs []issues.FieldDef
+	for _, d := range ds {
+		defs = append(defs, issues.FieldDef(d))
+	}
+	return defs, nil
+}
+
+// lookupFieldDef returns the definition of the field with the specified name.
+func lookupFieldDef(defs []issues.FieldDef, name string) (issues.FieldDef, bool) {
+	for _, d := range defs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return issues.FieldDef{}, false
+}
+
+// checkFields returns non-nil error if fields can't be set in a repo with defs.
+func checkFields(defs []issues.FieldDef, fields []issues.Field) error {
+	for _, f := range fields {
+		d, ok := lookupFieldDef(defs, f.Name)
+		if !ok {
+			return &issues.InvalidArgumentError{Field: f.Name, Reason: "no such field"}
+		}
+		if d.ReadOnly {
+			return &issues.InvalidArgumentError{Field: f.Name, Reason: "field is read-only"}
+		}
+		if err := d.Validate(f.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFields sets values of fields in cur, and returns the result, along with changes
+// for fields whose values changed, in order of fields.
+func setFields(defs []issues.FieldDef, cur []field, fields []issues.Field) ([]field, []fieldChange) {
+	var changes []fieldChange
+	for _, f := range fields {
+		d, _ := lookupFieldDef(defs, f.Name)
+		i := indexOfField(cur, f.Name)
+		var from *field
+		if i != -1 {
+			from = &cur[i]
+		}
+		if d.Compare(fieldValue(from), f.Value) == 0 {
+			continue
+		}
+		c := fieldChange{Name: f.Name}
+		if from != nil {
+			v := *from
+			c.From = &v
+		}
+		switch {
+		case f.Value == nil:
+			cur = append(cur[:i:i], cur[i+1:]...)
+		case i == -1:
+			to := fromField(f)
+			c.To = &to
+			cur = append(cur, to)
+		default:
+			to := fromField(f)
+			c.To = &to
+			cur[i] = to
+		}
+		changes = append(changes, c)
+	}
+	return cur, changes
+}
+
+func indexOfField(fs []field, name string) int {
+	for i, f := range fs {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// fieldValue returns the value of f, or nil if f is nil.
+func fieldValue(f *field) interface{} {
+	if f == nil {
+		return nil
+	}
+	return f.Field().Value
+}
+
+// fieldChangePtr converts an optional on-disk field change to *issues.FieldChange.
+func fieldChangePtr(c *fieldChange) *issues.FieldChange {
+	if c == nil {
+		return nil
+	}
+	return c.FieldChange()
+}
+
+// fromFields converts on-disk fields to issues.Fields.
+func fromFields(fs []field) []issues.Field {
+	var fields []issues.Field
+	for _, f := range fs {
+		fields = append(fields, f.Field())
+	}
+	return fields
+}
+
+// checkFilter returns non-nil error if filter refers to fields that aren't in defs,
+// or has values that aren't valid for them. A nil value matches unset fields.
+func checkFilter(defs []issues.FieldDef, filter []issues.Field) error {
+	for _, f := range filter {
+		d, ok := lookupFieldDef(defs, f.Name)
+		if !ok {
+			return &issues.InvalidArgumentError{Field: f.Name, Reason: "no such field"}
+		}
+		if err := d.Validate(f.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchFields reports whether an issue with fields matches filter.
+// filter must be valid, see checkFilter.
+func matchFields(defs []issues.FieldDef, fields []field, filter []issues.Field) bool {
+	for _, want := range filter {
+		d, _ := lookupFieldDef(defs, want.Name)
+		var got *field
+		if i := indexOfField(fields, want.Name); i != -1 {
+			got = &fields[i]
+		}
+		if d.Compare(fieldValue(got), want.Value) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sortByField sorts is by the custom field with the specified name, preserving
+// the order of issues with equal values. Issues where the field is unset come last.
+func sortByField(defs []issues.FieldDef, is []issues.Issue, name string, descending bool) error {
+	d, ok := lookupFieldDef(defs, name)
+	if !ok {
+		return &issues.InvalidArgumentError{Field: "Sort", Reason: fmt.Sprintf("no such field %q", name)}
+	}
+	value := func(i issues.Issue) interface{} { return issues.FieldValues(i.Fields)[name] }
+	sort.SliceStable(is, func(i, j int) bool {
+		a, b := value(is[i]), value(is[j])
+		if descending && a != nil && b != nil {
+			a, b = b, a
+		}
+		return d.Compare(a, b) < 0
+	})
+	return nil
+}
@@ -22,13 +22,42 @@ import (
 // NewService creates a virtual filesystem-backed issues.Service using root for storage.
 // It uses notifications service, if not nil.
 // It uses events service, if not nil.
-func NewService(root webdav.FileSystem, notifications notifications.ExternalService, events events.ExternalService, users users.Service) (issues.Service, error) {
-	return &service{
+func NewService(root webdav.FileSystem, notifications notifications.ExternalService, events events.ExternalService, users users.Service, opt *Options) (issues.Service, error) {
+	if opt == nil {
+		opt = new(Options)
+	}
+	s := &service{
 		fs:            root,
 		notifications: notifications,
 		events:        events,
 		users:         users,
-	}, nil
+		rtr:           opt.Router,
+		presenter:     opt.Presenter,
+		policy:        opt.Policy,
+	}
+	if s.rtr == nil {
+		s.rtr = DefaultRouter{}
+	}
+	if s.presenter == nil {
+		s.presenter = DefaultPresenter{}
+	}
+	if s.policy == nil {
+		s.policy = func(Mutation) bool { return true }
+	}
+	return s, nil
+}
+
+// Options are optional behaviors of the service.
+type Options struct {
+	// Router builds URLs of issues and comments. If nil, DefaultRouter is used.
+	Router Router
+
+	// Presenter presents notifications. If nil, DefaultPresenter is used.
+	Presenter Presenter
+
+	// Policy reports which mutations subscribers are notified of.
+	// If nil, subscribers are notified of all mutations.
+	Policy NotifyPolicy
 }
 
 type service struct {
@@ -40,7 +69,10 @@ type service struct {
 	// events may be nil if there's no events service.
 	events events.ExternalService
 
-	users users.Service
+	users     users.Service
+	rtr       Router
+	presenter Presenter
+	policy    NotifyPolicy
 }
 
 func (s *service) List(ctx context.Context, repo issues.RepoSpec, opt issues.IssueListOptions) ([]issues.Issue, error) {
@@ -53,6 +85,15 @@ func (s *service) List(ctx context.Context, repo issues.RepoSpec, opt issues.Iss
 
 	var is []issues.Issue
 
+	defs, err := s.fieldDefs(ctx, repo)
+	if err != nil {
+		return is, err
+	}
+	if err := checkFilter(defs, opt.Fields); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return is, err
+	}
+
 	dirs, err := readDirIDs(ctx, s.fs, issuesDir(repo))
 	if os.IsNotExist(err) {
 		dirs = nil
@@ -74,6 +115,9 @@ func (s *service) List(ctx context.Context, repo issues.RepoSpec, opt issues.Iss
 		if opt.State != issues.AllStates && issue.State != issues.State(opt.State) {
 			continue
 		}
+		if !matchFields(defs, issue.Fields, opt.Fields) {
+			continue
+		}
 
 		comments, err := readDirIDs(ctx, s.fs, issueDir(repo, dir.ID)) // Count comments.
 		if err != nil {
@@ -92,6 +136,7 @@ func (s *service) List(ctx context.Context, repo issues.RepoSpec, opt issues.Iss
 			State:  issue.State,
 			Title:  issue.Title,
 			Labels: labels,
+			Fields: fromFields(issue.Fields),
 			Comment: issues.Comment{
 				User:      s.user(ctx, author),
 				CreatedAt: issue.CreatedAt,
@@ -100,6 +145,13 @@ func (s *service) List(ctx context.Context, repo issues.RepoSpec, opt issues.Iss
 		})
 	}
 
+	if opt.Sort != "" {
+		err := sortByField(defs, is, opt.Sort, opt.SortDescending)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return is, nil
 }
 
@@ -113,6 +165,15 @@ func (s *service) Count(ctx context.Context, repo issues.RepoSpec, opt issues.Is
 
 	var count uint64
 
+	defs, err := s.fieldDefs(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkFilter(defs, opt.Fields); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return 0, err
+	}
+
 	dirs, err := readDirIDs(ctx, s.fs, issuesDir(repo))
 	if os.IsNotExist(err) {
 		dirs = nil
@@ -133,6 +194,9 @@ func (s *service) Count(ctx context.Context, repo issues.RepoSpec, opt issues.Is
 		if opt.State != issues.AllStates && issue.State != issues.State(opt.State) {
 			continue
 		}
+		if !matchFields(defs, issue.Fields, opt.Fields) {
+			continue
+		}
 
 		count++
 	}
@@ -159,7 +223,6 @@ func (s *service) Get(ctx context.Context, repo issues.RepoSpec, id uint64) (iss
 	if err != nil {
 		return issues.Issue{}, err
 	}
-	author := issue.Author.UserSpec()
 	var labels []issues.Label
 	for _, l := range issue.Labels {
 		labels = append(labels, issues.Label{
@@ -176,17 +239,13 @@ func (s *service) Get(ctx context.Context, repo issues.RepoSpec, id uint64) (iss
 		}
 	}
 
-	// TODO: Eliminate comment body properties from issues.Issue. It's missing increasingly more fields, like Edited, etc.
 	return issues.Issue{
-		ID:     id,
-		State:  issue.State,
-		Title:  issue.Title,
-		Labels: labels,
-		Comment: issues.Comment{
-			User:      s.user(ctx, author),
-			CreatedAt: issue.CreatedAt,
-			Editable:  nil == canEdit(currentUser, issue.Author),
-		},
+		ID:      id,
+		State:   issue.State,
+		Title:   issue.Title,
+		Labels:  labels,
+		Fields:  fromFields(issue.Fields),
+		Comment: s.comment(ctx, currentUser, 0, issue.comment),
 		Replies: len(comments) - 1,
 	}, nil
 }
@@ -213,40 +272,46 @@ func (s *service) ListComments(ctx context.Context, repo issues.RepoSpec, id uin
 			return comments, err
 		}
 
-		author := comment.Author.UserSpec()
-		var edited *issues.Edited
-		if ed := comment.Edited; ed != nil {
-			edited = &issues.Edited{
-				By: s.user(ctx, ed.By.UserSpec()),
-				At: ed.At,
-			}
-		}
-		var rs []reactions.Reaction
-		for _, cr := range comment.Reactions {
-			reaction := reactions.Reaction{
-				Reaction: cr.EmojiID,
-			}
-			for _, u := range cr.Authors {
-				reactionAuthor := u.UserSpec()
-				// TODO: Since we're potentially getting many of the same users multiple times here, consider caching them locally.
-				reaction.Users = append(reaction.Users, s.user(ctx, reactionAuthor))
-			}
-			rs = append(rs, reaction)
-		}
-		comments = append(comments, issues.Comment{
-			ID:        fi.ID,
-			User:      s.user(ctx, author),
-			CreatedAt: comment.CreatedAt,
-			Edited:    edited,
-			Body:      comment.Body,
-			Reactions: rs,
-			Editable:  nil == canEdit(currentUser, comment.Author),
-		})
+		comments = append(comments, s.comment(ctx, currentUser, fi.ID, comment))
 	}
 
 	return comments, nil
 }
 
+// comment converts stored comment c with the specified ID into issues.Comment,
+// as seen by currentUser.
+func (s *service) comment(ctx context.Context, currentUser users.User, id uint64, c comment) issues.Comment {
+	author := c.Author.UserSpec()
+	var edited *issues.Edited
+	if ed := c.Edited; ed != nil {
+		edited = &issues.Edited{
+			By: s.user(ctx, ed.By.UserSpec()),
+			At: ed.At,
+		}
+	}
+	var rs []reactions.Reaction
+	for _, cr := range c.Reactions {
+		reaction := reactions.Reaction{
+			Reaction: cr.EmojiID,
+		}
+		for _, u := range cr.Authors {
+			reactionAuthor := u.UserSpec()
+			// TODO: Since we're potentially getting many of the same users multiple times here, consider caching them locally.
+			reaction.Users = append(reaction.Users, s.user(ctx, reactionAuthor))
+		}
+		rs = append(rs, reaction)
+	}
+	return issues.Comment{
+		ID:        id,
+		User:      s.user(ctx, author),
+		CreatedAt: c.CreatedAt,
+		Edited:    edited,
+		Body:      c.Body,
+		Reactions: rs,
+		Editable:  nil == canEdit(currentUser, c.Author),
+	}
+}
+
 func (s *service) ListEvents(ctx context.Context, repo issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]issues.Event, error) {
 	s.fsMu.RLock()
 	defer s.fsMu.RUnlock()
@@ -280,6 +345,10 @@ func (s *service) ListEvents(ctx context.Context, repo issues.RepoSpec, id uint6
 			Close:     event.Close.Close(),
 			Rename:    event.Rename,
 			Label:     label,
+
+			CrossReference: event.CrossReference.CrossReference(),
+			Relation:       relationPtr(event.Relation),
+			FieldChange:    fieldChangePtr(event.FieldChange),
 		})
 	}
 
@@ -328,19 +397,20 @@ func (s *service) CreateComment(ctx context.Context, repo issues.RepoSpec, id ui
 	}
 
 	// Notify subscribed users.
-	// TODO: Come up with a better way to compute fragment; that logic shouldn't be duplicated here from issuesapp router.
-	err = s.notify(ctx, repo, id, fmt.Sprintf("comment-%d", commentID), author, comment.CreatedAt)
+	err = s.notify(ctx, repo, id, CommentCreated, s.rtr.IssueCommentURL(ctx, repo, id, commentID), nil, author, comment.CreatedAt)
 	if err != nil {
 		log.Println("service.CreateComment: failed to s.notify:", err)
 	}
 
 	// Log event.
-	// TODO: Come up with a better way to compute fragment; that logic shouldn't be duplicated here from issuesapp router.
-	err = s.logIssueComment(ctx, repo, id, fmt.Sprintf("comment-%d", commentID), currentUser, comment.CreatedAt, comment.Body)
+	err = s.logIssueComment(ctx, repo, id, s.rtr.IssueCommentURL(ctx, repo, id, commentID), currentUser, comment.CreatedAt, comment.Body)
 	if err != nil {
 		log.Println("service.CreateComment: failed to s.logIssueComment:", err)
 	}
 
+	// Record references to other issues.
+	s.crossReference(ctx, repo, id, s.rtr.IssueCommentURL(ctx, repo, id, commentID), author, comment.CreatedAt, "", comment.Body)
+
 	return issues.Comment{
 		ID:        commentID,
 		User:      s.user(ctx, author),
@@ -363,6 +433,14 @@ func (s *service) Create(ctx context.Context, repo issues.RepoSpec, i issues.Iss
 	if err := i.Validate(); err != nil {
 		return issues.Issue{}, err
 	}
+	if len(i.Assignees) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, &issues.InvalidArgumentError{Field: "Assignees", Reason: "assignees are not supported"}
+	}
+	if i.Milestone != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, &issues.InvalidArgumentError{Field: "Milestone", Reason: "milestones are not supported"}
+	}
 
 	s.fsMu.Lock()
 	defer s.fsMu.Unlock()
@@ -371,6 +449,18 @@ func (s *service) Create(ctx context.Context, repo issues.RepoSpec, i issues.Iss
 		return issues.Issue{}, err
 	}
 
+	var fields []field
+	if len(i.Fields) > 0 {
+		defs, err := s.fieldDefs(ctx, repo)
+		if err != nil {
+			return issues.Issue{}, err
+		}
+		if err := checkFields(defs, i.Fields); err != nil {
+			return issues.Issue{}, err
+		}
+		fields, _ = setFields(defs, nil, i.Fields)
+	}
+
 	var labels []label
 	for _, l := range i.Labels {
 		labels = append(labels, label{
@@ -382,6 +472,7 @@ func (s *service) Create(ctx context.Context, repo issues.RepoSpec, i issues.Iss
 		State:  issues.OpenState,
 		Title:  i.Title,
 		Labels: labels,
+		Fields: fields,
 		comment: comment{
 			Author:    fromUserSpec(currentUser.UserSpec),
 			CreatedAt: time.Now().UTC(),
@@ -416,21 +507,25 @@ func (s *service) Create(ctx context.Context, repo issues.RepoSpec, i issues.Iss
 	}
 
 	// Notify subscribed users.
-	err = s.notify(ctx, repo, issueID, "", author, issue.CreatedAt)
+	err = s.notify(ctx, repo, issueID, IssueCreated, s.rtr.IssueURL(ctx, repo, issueID), nil, author, issue.CreatedAt)
 	if err != nil {
 		log.Println("service.Create: failed to s.notify:", err)
 	}
 
 	// Log event.
-	err = s.logIssue(ctx, repo, issueID, "", issue, currentUser, "opened", issue.CreatedAt)
+	err = s.logIssue(ctx, repo, issueID, s.rtr.IssueURL(ctx, repo, issueID), issue, currentUser, "opened", issue.CreatedAt)
 	if err != nil {
 		log.Println("service.Create: failed to s.logIssue:", err)
 	}
 
+	// Record references to other issues.
+	s.crossReference(ctx, repo, issueID, s.rtr.IssueURL(ctx, repo, issueID), author, issue.CreatedAt, "", issue.Body)
+
 	return issues.Issue{
-		ID:    issueID,
-		State: issue.State,
-		Title: issue.Title,
+		ID:     issueID,
+		State:  issue.State,
+		Title:  issue.Title,
+		Fields: fromFields(issue.Fields),
 		Comment: issues.Comment{
 			ID:        0,
 			User:      s.user(ctx, author),
@@ -472,6 +567,35 @@ func canReact(currentUser users.UserSpec) error {
 }
 
 func (s *service) Edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir issues.IssueRequest) (issues.Issue, []issues.Event, error) {
+	return s.edit(ctx, repo, id, ir, issues.Close{Closer: nil})
+}
+
+// SetState implements issues.Closer.
+func (s *service) SetState(ctx context.Context, repo issues.RepoSpec, id uint64, state issues.State, c issues.Close) (issues.Issue, []issues.Event, error) {
+	switch c.Closer.(type) {
+	case issues.Change, issues.Commit, issues.IssueRef, nil:
+	default:
+		return issues.Issue{}, nil, fmt.Errorf("unsupported Closer type %T", c.Closer) // TODO: Map to 400 Bad Request HTTP error.
+	}
+	return s.edit(ctx, repo, id, issues.IssueRequest{State: &state}, c)
+}
+
+// checkUnsupported returns non-nil error if ir edits something
+// that this implementation doesn't support editing.
+func checkUnsupported(ir issues.IssueRequest) error {
+	switch {
+	case ir.Labels != nil:
+		return &issues.InvalidArgumentError{Field: "Labels", Reason: "editing labels is not supported"}
+	case ir.Assignees != nil:
+		return &issues.InvalidArgumentError{Field: "Assignees", Reason: "assignees are not supported"}
+	case ir.Milestone != nil:
+		return &issues.InvalidArgumentError{Field: "Milestone", Reason: "milestones are not supported"}
+	}
+	return nil
+}
+
+// edit edits the specified issue. c is attached to the Closed event, if ir closes the issue.
+func (s *service) edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir issues.IssueRequest, c issues.Close) (issues.Issue, []issues.Event, error) {
 	currentUser, err := s.users.GetAuthenticated(ctx)
 	if err != nil {
 		return issues.Issue{}, nil, err
@@ -483,6 +607,10 @@ func (s *service) Edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir
 	if err := ir.Validate(); err != nil {
 		return issues.Issue{}, nil, err
 	}
+	if err := checkUnsupported(ir); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, nil, err
+	}
 
 	s.fsMu.Lock()
 	defer s.fsMu.Unlock()
@@ -499,6 +627,17 @@ func (s *service) Edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir
 		return issues.Issue{}, nil, err
 	}
 
+	var defs []issues.FieldDef
+	if len(ir.Fields) > 0 {
+		defs, err = s.fieldDefs(ctx, repo)
+		if err != nil {
+			return issues.Issue{}, nil, err
+		}
+		if err := checkFields(defs, ir.Fields); err != nil {
+			return issues.Issue{}, nil, err
+		}
+	}
+
 	author := issue.Author.UserSpec()
 	actor := currentUser.UserSpec
 
@@ -511,6 +650,8 @@ func (s *service) Edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir
 	if ir.Title != nil {
 		issue.Title = *ir.Title
 	}
+	var fieldChanges []fieldChange
+	issue.Fields, fieldChanges = setFields(defs, issue.Fields, ir.Fields)
 
 	// Commit to storage.
 	err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, id, 0), issue)
@@ -518,30 +659,46 @@ func (s *service) Edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir
 		return issues.Issue{}, nil, err
 	}
 
-	// Create event and commit to storage.
-	event := event{
-		Actor:     fromUserSpec(actor),
-		CreatedAt: time.Now().UTC(),
-	}
-	// TODO: A single edit operation can result in multiple events, we should emit multiple events in such cases. We're currently emitting at most one event.
-	switch {
-	case ir.State != nil && *ir.State != origState:
+	// Create events and commit to storage.
+	// A single edit can result in multiple events, one per changed field.
+	// They're created in a deterministic order: state first, then title, then fields.
+	createdAt := time.Now().UTC()
+	var evs []event
+	if ir.State != nil && *ir.State != origState {
+		e := event{
+			Actor:     fromUserSpec(actor),
+			CreatedAt: createdAt,
+		}
 		switch *ir.State {
 		case issues.OpenState:
-			event.Type = issues.Reopened
+			e.Type = issues.Reopened
 		case issues.ClosedState:
-			event.Type = issues.Closed
-			event.Close = fromClose(issues.Close{Closer: nil})
-		}
-	case ir.Title != nil && *ir.Title != origTitle:
-		event.Type = issues.Renamed
-		event.Rename = &issues.Rename{
-			From: origTitle,
-			To:   *ir.Title,
+			e.Type = issues.Closed
+			e.Close = fromClose(c)
 		}
+		evs = append(evs, e)
+	}
+	if ir.Title != nil && *ir.Title != origTitle {
+		evs = append(evs, event{
+			Actor:     fromUserSpec(actor),
+			CreatedAt: createdAt,
+			Type:      issues.Renamed,
+			Rename: &issues.Rename{
+				From: origTitle,
+				To:   *ir.Title,
+			},
+		})
+	}
+	for i := range fieldChanges {
+		evs = append(evs, event{
+			Actor:       fromUserSpec(actor),
+			CreatedAt:   createdAt,
+			Type:        issues.FieldChanged,
+			FieldChange: &fieldChanges[i],
+		})
 	}
 	var events []issues.Event
-	if event.Type != "" {
+	for _, event := range evs {
 		eventID, err := nextID(ctx, s.fs, issueEventsDir(repo, id))
 		if err != nil {
 			return issues.Issue{}, nil, err
@@ -556,36 +713,41 @@ func (s *service) Edit(ctx context.Context, repo issues.RepoSpec, id uint64, ir
 			Actor:     s.user(ctx, actor),
 			CreatedAt: event.CreatedAt,
 			Type:      event.Type,
+			Close:     event.Close.Close(),
 			Rename:    event.Rename,
+
+			FieldChange: fieldChangePtr(event.FieldChange),
 		})
 	}
 
-	if ir.State != nil && *ir.State != origState {
+	if len(events) > 0 {
 		// Subscribe interested users.
 		err = s.subscribe(ctx, repo, id, actor, "")
 		if err != nil {
 			log.Println("service.Edit: failed to s.subscribe:", err)
 		}
+	}
+	for i, e := range events {
+		eventURL := s.rtr.IssueEventURL(ctx, repo, id, e.ID)
 
 		// Notify subscribed users.
-		// TODO: Maybe set fragment to fmt.Sprintf("event-%d", eventID), etc.
-		err = s.notify(ctx, repo, id, "", actor, event.CreatedAt)
+		err = s.notify(ctx, repo, id, eventMutation(e.Type), eventURL, &events[i], actor, e.CreatedAt)
 		if err != nil {
 			log.Println("service.Edit: failed to s.notify:", err)
 		}
 
 		// Log event.
-		// TODO: Maybe set fragment to fmt.Sprintf("event-%d", eventID), etc.
-		err = s.logIssue(ctx, repo, id, "", issue, currentUser, string(event.Type), event.CreatedAt)
+		err = s.logIssue(ctx, repo, id, eventURL, issue, currentUser, string(e.Type), e.CreatedAt)
 		if err != nil {
 			log.Println("service.Edit: failed to s.logIssue:", err)
 		}
 	}
 
 	return issues.Issue{
-		ID:    id,
-		State: issue.State,
-		Title: issue.Title,
+		ID:     id,
+		State:  issue.State,
+		Title:  issue.Title,
+		Fields: fromFields(issue.Fields),
 		Comment: issues.Comment{
 			ID:        0,
 			User:      s.user(ctx, author),
@@ -638,6 +800,7 @@ func (s *service) EditComment(ctx context.Context, repo issues.RepoSpec, id uint
 		editedAt := time.Now().UTC()
 
 		// Apply edits.
+		origBody := issue.Body
 		if cr.Body != nil {
 			issue.Body = *cr.Body
 			issue.Edited = &edited{
@@ -658,15 +821,7 @@ func (s *service) EditComment(ctx context.Context, repo issues.RepoSpec, id uint
 			return issues.Comment{}, err
 		}
 
-		if cr.Body != nil {
-			// Subscribe interested users.
-			err = s.subscribe(ctx, repo, id, actor, *cr.Body)
-			if err != nil {
-				log.Println("service.EditComment: failed to s.subscribe:", err)
-			}
-
-			// TODO: Notify _newly mentioned_ users.
-		}
+		s.commentEdited(ctx, repo, id, s.rtr.IssueURL(ctx, repo, id), cr, currentUser, editedAt, origBody)
 
 		var edited *issues.Edited
 		if ed := issue.Edited; ed != nil {
@@ -722,6 +877,7 @@ func (s *service) EditComment(ctx context.Context, repo issues.RepoSpec, id uint
 	editedAt := time.Now().UTC()
 
 	// Apply edits.
+	origBody := comment.Body
 	if cr.Body != nil {
 		comment.Body = *cr.Body
 		comment.Edited = &edited{
@@ -742,15 +898,7 @@ func (s *service) EditComment(ctx context.Context, repo issues.RepoSpec, id uint
 		return issues.Comment{}, err
 	}
 
-	if cr.Body != nil {
-		// Subscribe interested users.
-		err = s.subscribe(ctx, repo, id, actor, *cr.Body)
-		if err != nil {
-			log.Println("service.EditComment: failed to s.subscribe:", err)
-		}
-
-		// TODO: Notify _newly mentioned_ users.
-	}
+	s.commentEdited(ctx, repo, id, s.rtr.IssueCommentURL(ctx, repo, id, cr.ID), cr, currentUser, editedAt, origBody)
 
 	var edited *issues.Edited
 	if ed := comment.Edited; ed != nil {
@@ -782,6 +930,56 @@ func (s *service) EditComment(ctx context.Context, repo issues.RepoSpec, id uint
 	}, nil
 }
 
+// commentEdited subscribes and notifies users of an edit to a comment at commentURL,
+// and logs it. origBody is the body of the comment before the edit.
+func (s *service) commentEdited(ctx context.Context, repo issues.RepoSpec, id uint64, commentURL string, cr issues.CommentRequest, currentUser users.User, editedAt time.Time, origBody string) {
+	actor := currentUser.UserSpec
+
+	if cr.Body != nil {
+		// Subscribe interested users.
+		err := s.subscribe(ctx, repo, id, actor, *cr.Body)
+		if err != nil {
+			log.Println("service.EditComment: failed to s.subscribe:", err)
+		}
+
+		// Notify subscribed users. If they're not notified of edits,
+		// notify only the newly mentioned users.
+		if s.policy(CommentEdited) {
+			err = s.notify(ctx, repo, id, CommentEdited, commentURL, nil, actor, editedAt)
+			if err != nil {
+				log.Println("service.EditComment: failed to s.notify:", err)
+			}
+		} else {
+			err = s.notifyNewlyMentioned(ctx, repo, id, commentURL, actor, editedAt, origBody, *cr.Body)
+			if err != nil {
+				log.Println("service.EditComment: failed to s.notifyNewlyMentioned:", err)
+			}
+		}
+
+		// Log event.
+		err = s.logIssueAction(ctx, repo, id, commentURL, currentUser, "edited", editedAt)
+		if err != nil {
+			log.Println("service.EditComment: failed to s.logIssueAction:", err)
+		}
+
+		// Record newly added references to other issues.
+		s.crossReference(ctx, repo, id, commentURL, actor, editedAt, origBody, *cr.Body)
+	}
+	if cr.Reaction != nil {
+		// Notify subscribed users.
+		err := s.notify(ctx, repo, id, Reacted, commentURL, nil, actor, editedAt)
+		if err != nil {
+			log.Println("service.EditComment: failed to s.notify:", err)
+		}
+
+		// Log event.
+		err = s.logIssueAction(ctx, repo, id, commentURL, currentUser, "reacted", editedAt)
+		if err != nil {
+			log.Println("service.EditComment: failed to s.logIssueAction:", err)
+		}
+	}
+}
+
 func paginate(fis []fileInfoID, opt *issues.ListOptions) []fileInfoID {
 	if opt == nil {
 		return fis
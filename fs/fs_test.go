@@ -1,11 +1,19 @@
 package fs
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"reflect"
 	"testing"
 
+	eventpkg "github.com/shurcooL/events/event"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/notifications"
 	"github.com/shurcooL/reactions"
 	"github.com/shurcooL/users"
+	"github.com/shurcooL/webdavfs/vfsutil"
+	"golang.org/x/net/webdav"
 )
 
 func TestToggleReaction(t *testing.T) {
@@ -32,3 +40,702 @@ func TestToggleReaction(t *testing.T) {
 		t.Errorf("\ngot  %+v\nwant %+v", got.Reactions, want.Reactions)
 	}
 }
+
+func TestMentions(t *testing.T) {
+	body := "Hey @alice, see `@bob` and ``code with ` @carol``.\n" +
+		"\n" +
+		"```go\n" +
+		"// @dave\n" +
+		"```\n" +
+		"\n" +
+		"    @erin in indented code\n" +
+		"\n" +
+		"Email me at frank@example.com, cc @Alice and @grace-h.\n"
+
+	got := mentions(body)
+	want := []string{"alice", "grace-h"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	body := "Fixes #12 and golang/go#3, see also example.com/repo#7.\n" +
+		"Not `#13`, not issue#x, not http://example.com/page#14, not &#15;.\n" +
+		"\n" +
+		"    #16 in indented code\n" +
+		"\n" +
+		"(#12) again, and #0.\n"
+
+	got := references(body)
+	want := []reference{
+		{ID: 12},
+		{Repo: "golang/go", ID: 3},
+		{Repo: "example.com/repo", ID: 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCrossReference(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	s, err := NewService(webdav.NewMemFS(), nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		_, err := s.Create(ctx, repo, issues.Issue{Title: fmt.Sprint("Issue ", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A comment on issue 1 that mentions #2 twice, and itself, references issue 2 once.
+	_, err = s.CreateComment(ctx, repo, 1, issues.Comment{Body: "See #2, and #2 again. Also #1."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Editing it to mention nonexistent issue #3 references nothing new.
+	body := "See #2 and #3."
+	_, err = s.EditComment(ctx, repo, 1, issues.CommentRequest{ID: 1, Body: &body})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es, err := s.ListEvents(ctx, repo, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Type != issues.CrossReferenced || es[0].CrossReference == nil {
+		t.Fatalf("got events %+v on issue 2, want one CrossReferenced event", es)
+	}
+	want := issues.IssueRef{Repo: repo, ID: 1, Title: "Issue 1", HTMLURL: "https://example.com/repo/...$issues/1#comment-1"}
+	if got := es[0].CrossReference.Source; !reflect.DeepEqual(got, want) {
+		t.Errorf("got source %+v, want %+v", got, want)
+	}
+	es, err = s.ListEvents(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 0 {
+		t.Errorf("got events %+v on issue 1, want none", es)
+	}
+}
+
+func TestRelations(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	root := webdav.NewMemFS()
+	s, err := NewService(root, nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		_, err := s.Create(ctx, repo, issues.Issue{Title: fmt.Sprint("Issue ", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := s.(issues.Relater)
+
+	// 1 blocks 2, 2 blocks 3.
+	if _, err := r.AddRelation(ctx, repo, 1, issues.Relation{Type: issues.Blocks, Repo: repo, ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddRelation(ctx, repo, 3, issues.Relation{Type: issues.BlockedBy, Repo: repo, ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ListRelations(ctx, repo, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []issues.Relation{
+		{Type: issues.BlockedBy, Repo: repo, ID: 1},
+		{Type: issues.Blocks, Repo: repo, ID: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got relations %+v, want %+v", got, want)
+	}
+
+	// Users can't relate their issues to issues they can't edit.
+	other, err := NewService(root, nil, nil, mockUsers{ID: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Create(ctx, repo, issues.Issue{Title: "Issue 4"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.(issues.Relater).AddRelation(ctx, repo, 4, issues.Relation{Type: issues.Blocks, Repo: repo, ID: 1}); !os.IsPermission(err) {
+		t.Errorf("got error %v relating to another user's issue, want permission error", err)
+	}
+	if _, err := other.(issues.Relater).RemoveRelation(ctx, repo, 4, issues.Relation{Type: issues.Blocks, Repo: repo, ID: 1}); !os.IsPermission(err) {
+		t.Errorf("got error %v unrelating from another user's issue, want permission error", err)
+	}
+
+	// 3 blocks 1 would be a cycle.
+	if _, err := r.AddRelation(ctx, repo, 3, issues.Relation{Type: issues.Blocks, Repo: repo, ID: 1}); err == nil {
+		t.Error("AddRelation created a cycle")
+	}
+
+	// Marking 3 as a duplicate of 1 closes it.
+	events, err := r.AddRelation(ctx, repo, 3, issues.Relation{Type: issues.DuplicateOf, Repo: repo, ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != issues.Related || events[1].Type != issues.Closed {
+		t.Fatalf("got events %+v, want related and closed", events)
+	}
+	if ref, ok := events[1].Close.Closer.(issues.IssueRef); !ok || ref.ID != 1 || ref.Title != "Issue 1" {
+		t.Errorf("got closer %+v, want issue 1", events[1].Close.Closer)
+	}
+	issue, err := s.Get(ctx, repo, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.State != issues.ClosedState {
+		t.Errorf("got state %q, want %q", issue.State, issues.ClosedState)
+	}
+
+	// Removing a relation removes it from both ends.
+	if _, err := r.RemoveRelation(ctx, repo, 2, issues.Relation{Type: issues.BlockedBy, Repo: repo, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = r.ListRelations(ctx, repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []issues.Relation{{Type: issues.DuplicatedBy, Repo: repo, ID: 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got relations %+v, want %+v", got, want)
+	}
+	es, err := s.ListEvents(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 3 || es[2].Type != issues.Unrelated || es[2].Relation.Type != issues.Blocks {
+		t.Errorf("got events %+v, want related, related and unrelated", es)
+	}
+}
+
+func TestSetState(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	s, err := NewService(webdav.NewMemFS(), nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.(issues.Closer)
+
+	commit := issues.Commit{SHA: "abc123", Message: "Fixes #1.", HTMLURL: "https://example.com/commit/abc123"}
+	if _, _, err := c.SetState(ctx, repo, 1, issues.ClosedState, issues.Close{Closer: commit}); err != nil {
+		t.Fatal(err)
+	}
+	// Closing a closed issue creates no events.
+	_, events, err := c.SetState(ctx, repo, 1, issues.ClosedState, issues.Close{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("got events %+v closing a closed issue, want none", events)
+	}
+	if _, _, err := c.SetState(ctx, repo, 1, issues.ClosedState, issues.Close{Closer: "commit"}); err == nil {
+		t.Error("SetState accepted an unsupported closer")
+	}
+
+	// The closer is persisted on the Closed event.
+	es, err := s.ListEvents(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Type != issues.Closed {
+		t.Fatalf("got events %+v, want a Closed event", es)
+	}
+	if got := es[0].Close.Closer; !reflect.DeepEqual(got, commit) {
+		t.Errorf("got closer %+v, want %+v", got, commit)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	s, err := NewService(webdav.NewMemFS(), nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing and renaming in one edit creates a Closed and a Renamed event, in that order.
+	state, title := issues.ClosedState, "Renamed issue"
+	issue, events, err := s.Edit(ctx, repo, 1, issues.IssueRequest{State: &state, Title: &title})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.State != issues.ClosedState || issue.Title != title {
+		t.Errorf("got issue state %q and title %q, want %q and %q", issue.State, issue.Title, state, title)
+	}
+	want := []issues.EventType{issues.Closed, issues.Renamed}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("got returned events %v, want %v", got, want)
+	}
+	persisted, err := s.ListEvents(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventTypes(persisted); !reflect.DeepEqual(got, want) {
+		t.Errorf("got persisted events %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(events, persisted) {
+		t.Errorf("got persisted events %+v, want the returned events %+v", persisted, events)
+	}
+	if len(persisted) == 2 && (persisted[1].Rename == nil || *persisted[1].Rename != (issues.Rename{From: "Issue", To: title})) {
+		t.Errorf("got rename %+v, want from %q to %q", persisted[1].Rename, "Issue", title)
+	}
+}
+
+func eventTypes(es []issues.Event) []issues.EventType {
+	var ts []issues.EventType
+	for _, e := range es {
+		ts = append(ts, e.Type)
+	}
+	return ts
+}
+
+func TestSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	ns := new(mockNotifications)
+	s, err := NewService(webdav.NewMemFS(), ns, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := s.(issues.Subscriber)
+
+	// The author is subscribed to their issue, and can unsubscribe.
+	if got := subscriberIDs(t, sub, repo, 1); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("got subscribers %v, want [1]", got)
+	}
+	if err := sub.Unsubscribe(ctx, repo, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := subscriberIDs(t, sub, repo, 1); len(got) != 0 {
+		t.Errorf("got subscribers %v, want none", got)
+	}
+
+	// Watching a repo is separate from subscribing to its issues.
+	if err := sub.Subscribe(ctx, repo, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := subscriberIDs(t, sub, repo, 0); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("got repo watchers %v, want [1]", got)
+	}
+	if got := subscriberIDs(t, sub, repo, 1); len(got) != 0 {
+		t.Errorf("got subscribers %v, want none", got)
+	}
+
+	if err := sub.Subscribe(ctx, repo, 2); err == nil {
+		t.Error("Subscribe to a nonexistent issue succeeded")
+	}
+}
+
+func TestNotifyNewlyMentioned(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	ns := new(mockNotifications)
+	policy := func(m Mutation) bool { return m != CommentEdited }
+	s, err := NewService(webdav.NewMemFS(), ns, nil, loginUsers{ID: 1}, &Options{Policy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Issue", Comment: issues.Comment{Body: "Body"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := ns.key(notifications.RepoSpec(repo), threadType, 1)
+	ns.notified = nil
+
+	// Subscribers aren't notified of edits that don't mention anyone new.
+	body := "Edited body"
+	_, err = s.EditComment(ctx, repo, 1, issues.CommentRequest{ID: 0, Body: &body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ns.notified[k]; len(got) != 0 {
+		t.Errorf("got notified users %v, want none", got)
+	}
+
+	// Newly mentioned users are notified, even without the UserNotifier extension.
+	body = "Edited body, cc @user2"
+	_, err = s.EditComment(ctx, repo, 1, issues.CommentRequest{ID: 0, Body: &body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ns.notified[k]; !containsUserSpec(got, users.UserSpec{ID: 2}) {
+		t.Errorf("got notified users %v, want them to include user 2", got)
+	}
+}
+
+func subscriberIDs(t *testing.T, sub issues.Subscriber, repo issues.RepoSpec, id uint64) []uint64 {
+	t.Helper()
+	us, err := sub.ListSubscribers(context.Background(), repo, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for _, u := range us {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestTemplates(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	root := webdav.NewMemFS()
+	if err := vfsutil.MkdirAll(ctx, root, templatesDir(repo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := jsonEncodeFile(ctx, root, fieldDefsPath(repo), []fieldDef{
+		{Name: "Version", Type: issues.StringField},
+		{Name: "Severity", Type: issues.EnumField, Options: []string{"Low", "High"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = jsonEncodeFile(ctx, root, templatePath(repo, "bug"), template{
+		TitlePrefix: "bug: ",
+		Body:        "### Steps to reproduce\n",
+		Labels:      []label{{Name: "Bug", Color: rgb{R: 0xff}}},
+		Fields:      []templateField{{Name: "Version", Required: true}, {Name: "Severity"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(root, nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := s.(issues.TemplateLister)
+
+	ts, err := tl.ListTemplates(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Name != "bug" || len(ts[0].Fields) != 2 {
+		t.Fatalf("got templates %+v, want the bug template", ts)
+	}
+
+	// Required fields must be set.
+	_, err = tl.CreateFromTemplate(ctx, repo, "bug", issues.Issue{Title: "Crash", Comment: issues.Comment{Body: "Version: go1.21"}})
+	if e, ok := err.(*issues.InvalidArgumentError); !ok || e.Field != "Fields" {
+		t.Errorf("got error %v, want *issues.InvalidArgumentError for Fields", err)
+	}
+
+	issue, err := tl.CreateFromTemplate(ctx, repo, "bug", issues.Issue{
+		Title:  "Crash",
+		Fields: []issues.Field{{Name: "Version", Value: "go1.21"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := issue.Title, "bug: Crash"; got != want {
+		t.Errorf("got title %q, want %q", got, want)
+	}
+	is, err := s.List(ctx, repo, issues.IssueListOptions{State: issues.AllStates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 1 || len(is[0].Labels) != 1 || is[0].Labels[0].Name != "Bug" {
+		t.Errorf("got issues %+v, want one with the Bug label", is)
+	}
+	if got := issues.FieldValues(is[0].Fields)["Version"]; got != "go1.21" {
+		t.Errorf("got Version %v, want go1.21", got)
+	}
+
+	_, err = tl.CreateFromTemplate(ctx, repo, "../bug", issues.Issue{Title: "Crash"})
+	if _, ok := err.(*issues.InvalidArgumentError); !ok {
+		t.Errorf("got error %v, want *issues.InvalidArgumentError", err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	root := webdav.NewMemFS()
+	if err := vfsutil.MkdirAll(ctx, root, repo.URI, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := jsonEncodeFile(ctx, root, fieldDefsPath(repo), []fieldDef{
+		{Name: "Priority", Type: issues.EnumField, Options: []string{"P0", "P1", "P2"}},
+		{Name: "Estimate", Type: issues.IntField},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(root, nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"P2", "P0", ""} {
+		i := issues.Issue{Title: "Issue " + p}
+		if p != "" {
+			i.Fields = []issues.Field{{Name: "Priority", Value: p}}
+		}
+		if _, err := s.Create(ctx, repo, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, err = s.Edit(ctx, repo, 1, issues.IssueRequest{Fields: []issues.Field{{Name: "Priority", Value: "P3"}}})
+	if e, ok := err.(*issues.InvalidArgumentError); !ok || e.Field != "Priority" {
+		t.Errorf("got error %v, want *issues.InvalidArgumentError for Priority", err)
+	}
+
+	_, events, err := s.Edit(ctx, repo, 1, issues.IssueRequest{Fields: []issues.Field{
+		{Name: "Priority", Value: "P1"},
+		{Name: "Estimate", Value: int64(3)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != issues.FieldChanged ||
+		events[0].FieldChange.From != "P2" || events[0].FieldChange.To != "P1" ||
+		events[1].FieldChange.From != nil || events[1].FieldChange.To != int64(3) {
+		t.Errorf("got events %+v, want Priority P2 -> P1 and Estimate unset -> 3", events)
+	}
+
+	is, err := s.List(ctx, repo, issues.IssueListOptions{State: issues.AllStates, Sort: "Priority"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for _, i := range is {
+		ids = append(ids, i.ID)
+	}
+	if got, want := ids, []uint64{2, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues %v sorted by priority, want %v", got, want)
+	}
+
+	is, err = s.List(ctx, repo, issues.IssueListOptions{State: issues.AllStates, Fields: []issues.Field{{Name: "Priority", Value: "P1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 1 || is[0].ID != 1 {
+		t.Errorf("got issues %+v, want issue 1 with priority P1", is)
+	}
+
+	// Invalid filters are rejected, rather than matching the wrong issues.
+	for _, f := range []issues.Field{
+		{Name: "Estimate", Value: 3},
+		{Name: "Priority", Value: "P3"},
+		{Name: "Severity", Value: nil},
+	} {
+		opt := issues.IssueListOptions{State: issues.AllStates, Fields: []issues.Field{f}}
+		if _, err := s.List(ctx, repo, opt); !isInvalidArgument(err) {
+			t.Errorf("List with filter %+v: got error %v, want *issues.InvalidArgumentError", f, err)
+		}
+		if _, err := s.Count(ctx, repo, opt); !isInvalidArgument(err) {
+			t.Errorf("Count with filter %+v: got error %v, want *issues.InvalidArgumentError", f, err)
+		}
+	}
+}
+
+func isInvalidArgument(err error) bool {
+	_, ok := err.(*issues.InvalidArgumentError)
+	return ok
+}
+
+func TestLabels(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	s, err := NewService(webdav.NewMemFS(), nil, nil, mockAdmin{mockUsers{ID: 1}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lm := s.(issues.LabelManager)
+
+	bug := issues.Label{Name: "bug", Color: issues.RGB{R: 0xff}, Description: "Something isn't working."}
+	if _, err := lm.CreateLabel(ctx, repo, bug); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lm.CreateLabel(ctx, repo, issues.Label{Name: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lm.CreateLabel(ctx, repo, bug); err == nil {
+		t.Error("creating a duplicate label succeeded, want error")
+	}
+	for _, ls := range [][]issues.Label{{bug}, {bug, {Name: "docs"}}} {
+		_, err := s.Create(ctx, repo, issues.Issue{Title: "Issue", Labels: ls})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name, color := "Bug", issues.RGB{R: 0xee}
+	l, err := lm.EditLabel(ctx, repo, "bug", issues.LabelRequest{Name: &name, Color: &color})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (issues.Label{Name: "Bug", Color: color, Description: bug.Description}); l != want {
+		t.Errorf("got edited label %+v, want %+v", l, want)
+	}
+	if err := lm.DeleteLabel(ctx, repo, "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	ls, err := lm.ListLabels(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []issues.Label{l}; !reflect.DeepEqual(ls, want) {
+		t.Errorf("got labels %+v, want %+v", ls, want)
+	}
+	is, err := s.List(ctx, repo, issues.IssueListOptions{State: issues.AllStates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range is {
+		if want := []issues.Label{{Name: "Bug", Color: color}}; !reflect.DeepEqual(i.Labels, want) {
+			t.Errorf("issue %d: got labels %+v, want %+v", i.ID, i.Labels, want)
+		}
+	}
+
+	s, err = NewService(webdav.NewMemFS(), nil, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.(issues.LabelManager).CreateLabel(ctx, repo, issues.Label{Name: "feature"}); !os.IsPermission(err) {
+		t.Errorf("got error %v for a non-admin, want permission error", err)
+	}
+}
+
+// mockNotifications is a notifications.ExternalService that tracks subscriptions,
+// which subscribers were notified, and the notification requests it was given.
+type mockNotifications struct {
+	subscribers map[string][]users.UserSpec
+	notified    map[string][]users.UserSpec
+	requests    []notifications.NotificationRequest
+}
+
+func (mockNotifications) key(repo notifications.RepoSpec, threadType string, threadID uint64) string {
+	return fmt.Sprint(repo.URI, "/", threadType, "/", threadID)
+}
+
+func (ns *mockNotifications) Subscribe(_ context.Context, repo notifications.RepoSpec, threadType string, threadID uint64, subscribers []users.UserSpec) error {
+	if ns.subscribers == nil {
+		ns.subscribers = make(map[string][]users.UserSpec)
+	}
+	k := ns.key(repo, threadType, threadID)
+	for _, u := range subscribers {
+		if !containsUserSpec(ns.subscribers[k], u) {
+			ns.subscribers[k] = append(ns.subscribers[k], u)
+		}
+	}
+	return nil
+}
+
+func (ns *mockNotifications) Unsubscribe(_ context.Context, repo notifications.RepoSpec, threadType string, threadID uint64, subscribers []users.UserSpec) error {
+	k := ns.key(repo, threadType, threadID)
+	var kept []users.UserSpec
+	for _, u := range ns.subscribers[k] {
+		if !containsUserSpec(subscribers, u) {
+			kept = append(kept, u)
+		}
+	}
+	ns.subscribers[k] = kept
+	return nil
+}
+
+func (ns *mockNotifications) ListSubscribers(_ context.Context, repo notifications.RepoSpec, threadType string, threadID uint64) ([]users.UserSpec, error) {
+	return ns.subscribers[ns.key(repo, threadType, threadID)], nil
+}
+
+func (*mockNotifications) MarkRead(context.Context, notifications.RepoSpec, string, uint64) error {
+	return nil
+}
+
+func (ns *mockNotifications) Notify(_ context.Context, repo notifications.RepoSpec, threadType string, threadID uint64, nr notifications.NotificationRequest) error {
+	if ns.notified == nil {
+		ns.notified = make(map[string][]users.UserSpec)
+	}
+	k := ns.key(repo, threadType, threadID)
+	ns.notified[k] = append(ns.notified[k], ns.subscribers[k]...)
+	ns.requests = append(ns.requests, nr)
+	return nil
+}
+
+// mockEvents is an events.ExternalService that records logged events.
+type mockEvents []eventpkg.Event
+
+func (es *mockEvents) Log(_ context.Context, e eventpkg.Event) error {
+	*es = append(*es, e)
+	return nil
+}
+
+// mockUsers is a users.Service where the specified user is authenticated.
+type mockUsers users.UserSpec
+
+func (mockUsers) Get(_ context.Context, user users.UserSpec) (users.User, error) {
+	return users.User{UserSpec: user, Login: fmt.Sprint("user", user.ID)}, nil
+}
+
+func (us mockUsers) GetAuthenticatedSpec(context.Context) (users.UserSpec, error) {
+	return users.UserSpec(us), nil
+}
+
+func (us mockUsers) GetAuthenticated(ctx context.Context) (users.User, error) {
+	return us.Get(ctx, users.UserSpec(us))
+}
+
+func (mockUsers) Edit(context.Context, users.EditRequest) (users.User, error) {
+	return users.User{}, fmt.Errorf("Edit: not implemented")
+}
+
+// mockAdmin is a users.Service where the specified user is authenticated and is a site admin.
+type mockAdmin struct{ mockUsers }
+
+func (us mockAdmin) GetAuthenticated(ctx context.Context) (users.User, error) {
+	u, err := us.mockUsers.GetAuthenticated(ctx)
+	u.SiteAdmin = true
+	return u, err
+}
+
+// loginUsers is a mockUsers that implements LoginResolver.
+type loginUsers mockUsers
+
+func (us loginUsers) Get(ctx context.Context, user users.UserSpec) (users.User, error) {
+	return mockUsers(us).Get(ctx, user)
+}
+
+func (us loginUsers) GetAuthenticatedSpec(ctx context.Context) (users.UserSpec, error) {
+	return mockUsers(us).GetAuthenticatedSpec(ctx)
+}
+
+func (us loginUsers) GetAuthenticated(ctx context.Context) (users.User, error) {
+	return mockUsers(us).GetAuthenticated(ctx)
+}
+
+func (us loginUsers) Edit(ctx context.Context, er users.EditRequest) (users.User, error) {
+	return mockUsers(us).Edit(ctx, er)
+}
+
+func (loginUsers) GetByLogin(_ context.Context, login string) (users.User, error) {
+	var id uint64
+	if _, err := fmt.Sscanf(login, "user%d", &id); err != nil {
+		return users.User{}, os.ErrNotExist
+	}
+	return users.User{UserSpec: users.UserSpec{ID: id}, Login: login}, nil
+}
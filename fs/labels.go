@@ -0,0 +1,217 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// ListLabels implements issues.LabelManager.
+func (s *service) ListLabels(ctx context.Context, repo issues.RepoSpec) ([]issues.Label, error) {
+	s.fsMu.RLock()
+	defer s.fsMu.RUnlock()
+
+	ls, err := s.labelCatalog(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	var labels []issues.Label
+	for _, l := range ls {
+		labels = append(labels, l.Label())
+	}
+	return labels, nil
+}
+
+// CreateLabel implements issues.LabelManager.
+func (s *service) CreateLabel(ctx context.Context, repo issues.RepoSpec, l issues.Label) (issues.Label, error) {
+	if err := l.Validate(); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Label{}, err
+	}
+	currentUser, err := s.users.GetAuthenticated(ctx)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	if err := canManageLabels(currentUser); err != nil {
+		return issues.Label{}, err
+	}
+
+	s.fsMu.Lock()
+	defer s.fsMu.Unlock()
+
+	ls, err := s.labelCatalog(ctx, repo)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	if indexOfLabel(ls, l.Name) != -1 {
+		return issues.Label{}, &issues.InvalidArgumentError{Field: "Name", Reason: fmt.Sprintf("label %q already exists", l.Name)}
+	}
+	if err := s.createNamespace(ctx, repo); err != nil {
+		return issues.Label{}, err
+	}
+	err = s.putLabelCatalog(ctx, repo, append(ls, fromLabel(l)))
+	if err != nil {
+		return issues.Label{}, err
+	}
+	return l, nil
+}
+
+// EditLabel implements issues.LabelManager.
+// Renaming or recoloring a label updates it on all issues in repo that have it.
+func (s *service) EditLabel(ctx context.Context, repo issues.RepoSpec, name string, lr issues.LabelRequest) (issues.Label, error) {
+	if err := lr.Validate(); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Label{}, err
+	}
+	currentUser, err := s.users.GetAuthenticated(ctx)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	if err := canManageLabels(currentUser); err != nil {
+		return issues.Label{}, err
+	}
+
+	s.fsMu.Lock()
+	defer s.fsMu.Unlock()
+
+	ls, err := s.labelCatalog(ctx, repo)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	i := indexOfLabel(ls, name)
+	if i == -1 {
+		return issues.Label{}, os.ErrNotExist
+	}
+	l := ls[i]
+	if lr.Name != nil && *lr.Name != name {
+		if indexOfLabel(ls, *lr.Name) != -1 {
+			return issues.Label{}, &issues.InvalidArgumentError{Field: "Name", Reason: fmt.Sprintf("label %q already exists", *lr.Name)}
+		}
+		l.Name = *lr.Name
+	}
+	if lr.Color != nil {
+		l.Color = fromRGB(*lr.Color)
+	}
+	if lr.Description != nil {
+		l.Description = *lr.Description
+	}
+	ls[i] = l
+	err = s.putLabelCatalog(ctx, repo, ls)
+	if err != nil {
+		return issues.Label{}, err
+	}
+
+	// Propagate to issues.
+	err = s.replaceIssueLabels(ctx, repo, name, &label{Name: l.Name, Color: l.Color})
+	if err != nil {
+		return issues.Label{}, err
+	}
+	return l.Label(), nil
+}
+
+// DeleteLabel implements issues.LabelManager.
+// Deleting a label removes it from all issues in repo that have it.
+func (s *service) DeleteLabel(ctx context.Context, repo issues.RepoSpec, name string) error {
+	currentUser, err := s.users.GetAuthenticated(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canManageLabels(currentUser); err != nil {
+		return err
+	}
+
+	s.fsMu.Lock()
+	defer s.fsMu.Unlock()
+
+	ls, err := s.labelCatalog(ctx, repo)
+	if err != nil {
+		return err
+	}
+	i := indexOfLabel(ls, name)
+	if i == -1 {
+		return os.ErrNotExist
+	}
+	err = s.putLabelCatalog(ctx, repo, append(ls[:i:i], ls[i+1:]...))
+	if err != nil {
+		return err
+	}
+
+	// Propagate to issues.
+	return s.replaceIssueLabels(ctx, repo, name, nil)
+}
+
+// canManageLabels returns nil error if currentUser is authorized to manage the label catalog.
+// It returns os.ErrPermission or an error that happened in other cases.
+func canManageLabels(currentUser users.User) error {
+	if !currentUser.SiteAdmin {
+		// Only site admins can manage labels, since doing so affects all issues.
+		return os.ErrPermission
+	}
+	return nil
+}
+
+// labelCatalog reads the label catalog of repo.
+// It returns no labels if repo has no catalog.
+func (s *service) labelCatalog(ctx context.Context, repo issues.RepoSpec) ([]label, error) {
+	var ls []label
+	err := jsonDecodeFile(ctx, s.fs, labelsPath(repo), &ls)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return ls, err
+}
+
+// putLabelCatalog writes ls, sorted by name, as the label catalog of repo.
+func (s *service) putLabelCatalog(ctx context.Context, repo issues.RepoSpec, ls []label) error {
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Name < ls[j].Name })
+	return jsonEncodeFile(ctx, s.fs, labelsPath(repo), ls)
+}
+
+// replaceIssueLabels replaces the label with the specified name with l
+// on all issues in repo that have it. If l is nil, the label is removed.
+func (s *service) replaceIssueLabels(ctx context.Context, repo issues.RepoSpec, name string, l *label) error {
+	dirs, err := readDirIDs(ctx, s.fs, issuesDir(repo))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		var issue issue
+		err := jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, dir.ID, 0), &issue)
+		if err != nil {
+			return err
+		}
+		i := indexOfLabel(issue.Labels, name)
+		if i == -1 {
+			continue
+		}
+		switch l {
+		case nil:
+			issue.Labels = append(issue.Labels[:i:i], issue.Labels[i+1:]...)
+		default:
+			issue.Labels[i] = *l
+		}
+		err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, dir.ID, 0), issue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexOfLabel(ls []label, name string) int {
+	for i, l := range ls {
+		if l.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,188 @@
+package fs
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/notifications"
+	"github.com/shurcooL/users"
+)
+
+// LoginResolver is an optional interface that the users.Service given to NewService
+// can implement to resolve @mentions of users that haven't participated in an issue.
+// Without it, only @mentions of issue participants are resolved.
+type LoginResolver interface {
+	// GetByLogin fetches the user with the specified login.
+	GetByLogin(ctx context.Context, login string) (users.User, error)
+}
+
+// UserNotifier is an optional interface that the notifications.ExternalService given to NewService
+// can implement to notify specific users rather than all subscribers of a thread.
+// It's used to notify users that are newly @mentioned when a comment is edited.
+// Without it, all subscribers are notified when users are newly mentioned.
+type UserNotifier interface {
+	// NotifyUsers notifies the specified users of a notification in the specified thread.
+	NotifyUsers(ctx context.Context, repo notifications.RepoSpec, threadType string, threadID uint64, nr notifications.NotificationRequest, users []users.UserSpec) error
+}
+
+// mentionedUsers returns users @mentioned in body of the specified issue.
+// Logins that don't resolve to a user are skipped.
+func (s *service) mentionedUsers(ctx context.Context, repo issues.RepoSpec, issueID uint64, body string) ([]users.UserSpec, error) {
+	logins := mentions(body)
+	if len(logins) == 0 {
+		return nil, nil
+	}
+
+	if lr, ok := s.users.(LoginResolver); ok {
+		var us []users.UserSpec
+		for _, login := range logins {
+			u, err := lr.GetByLogin(ctx, login)
+			if err != nil {
+				continue
+			}
+			us = append(us, u.UserSpec)
+		}
+		return us, nil
+	}
+
+	// Fall back to matching against logins of issue participants.
+	fis, err := readDirIDs(ctx, s.fs, issueDir(repo, issueID))
+	if err != nil {
+		return nil, err
+	}
+	participants := make(map[string]users.UserSpec)
+	for _, fi := range fis {
+		var comment comment
+		err := jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, issueID, fi.ID), &comment)
+		if err != nil {
+			return nil, err
+		}
+		author := comment.Author.UserSpec()
+		participants[strings.ToLower(s.user(ctx, author).Login)] = author
+	}
+	var us []users.UserSpec
+	for _, login := range logins {
+		if u, ok := participants[strings.ToLower(login)]; ok {
+			us = append(us, u)
+		}
+	}
+	return us, nil
+}
+
+// mentionRE matches an @mention. The character before it, if any,
+// is matched too, so that email addresses aren't mistaken for mentions.
+var mentionRE = regexp.MustCompile(`(^|[^A-Za-z0-9_\x60])@([A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)`)
+
+// mentions returns unique logins @mentioned in Markdown body, in order of appearance.
+// Mentions inside code blocks and inline code spans are ignored.
+func mentions(body string) []string {
+	var (
+		logins []string
+		seen   = make(map[string]bool)
+	)
+	for _, line := range proseLines(body) {
+		for _, m := range mentionRE.FindAllStringSubmatch(line, -1) {
+			login := m[2]
+			if seen[strings.ToLower(login)] {
+				continue
+			}
+			seen[strings.ToLower(login)] = true
+			logins = append(logins, login)
+		}
+	}
+	return logins
+}
+
+// proseLines returns lines of Markdown body that aren't in code blocks,
+// with inline code spans replaced by spaces.
+func proseLines(body string) []string {
+	var (
+		lines []string
+		fence string // Opening fence of the current fenced code block, if any.
+		blank = true // Whether the previous line is blank.
+	)
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" \t\r") == "" {
+				fence = ""
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			for _, c := range trimmed[3:] {
+				if byte(c) != fence[0] {
+					break
+				}
+				fence += string(c)
+			}
+			continue
+		case blank && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			// Indented code block. It continues while lines remain indented.
+			continue
+		}
+		blank = strings.TrimSpace(line) == ""
+		lines = append(lines, stripCodeSpans(line))
+	}
+	return lines
+}
+
+// stripCodeSpans replaces inline code spans in line with spaces.
+// A code span starts with a run of backticks and ends with a run of equal length.
+func stripCodeSpans(line string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(line, '`')
+		if i == -1 {
+			b.WriteString(line)
+			return b.String()
+		}
+		n := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+		delim := line[i : i+n]
+		end := -1
+		for j := i + n; j < len(line); {
+			k := strings.Index(line[j:], delim)
+			if k == -1 {
+				break
+			}
+			k += j
+			if k+n == len(line) || line[k+n] != '`' {
+				end = k
+				break
+			}
+			j = k + n + len(line[k+n:]) - len(strings.TrimLeft(line[k+n:], "`"))
+		}
+		if end == -1 {
+			// Unmatched backticks are literal.
+			b.WriteString(line[:i+n])
+			line = line[i+n:]
+			continue
+		}
+		b.WriteString(line[:i])
+		b.WriteString(strings.Repeat(" ", end+n-i))
+		line = line[end+n:]
+	}
+}
+
+// newlyMentioned returns users in after that aren't in before.
+func newlyMentioned(before, after []users.UserSpec) []users.UserSpec {
+	var us []users.UserSpec
+	for _, u := range after {
+		if !containsUserSpec(before, u) && !containsUserSpec(us, u) {
+			us = append(us, u)
+		}
+	}
+	return us
+}
+
+func containsUserSpec(set []users.UserSpec, e users.UserSpec) bool {
+	for _, v := range set {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,6 @@ package fs
 
 import (
 	"context"
-	"fmt"
-	"strings"
 	"time"
 
 	"github.com/shurcooL/issues"
@@ -25,12 +23,11 @@ func (s *service) subscribe(ctx context.Context, repo issues.RepoSpec, issueID u
 
 	subscribers := []users.UserSpec{user}
 
-	// TODO: Find mentioned users in body.
-	/*mentions, err := mentions(ctx, body)
+	mentions, err := s.mentionedUsers(ctx, repo, issueID, body)
 	if err != nil {
 		return err
 	}
-	subscribers = append(subscribers, mentions...)*/
+	subscribers = append(subscribers, mentions...)
 
 	return s.notifications.Subscribe(ctx, notifications.RepoSpec(repo), threadType, issueID, subscribers)
 }
@@ -44,85 +41,136 @@ func (s *service) markRead(ctx context.Context, repo issues.RepoSpec, issueID ui
 	return s.notifications.MarkRead(ctx, notifications.RepoSpec(repo), threadType, issueID)
 }
 
-// notify notifies all subscribed users of an update that shows up in their Notification Center.
-func (s *service) notify(ctx context.Context, repo issues.RepoSpec, issueID uint64, fragment string, actor users.UserSpec, time time.Time) error {
-	if s.notifications == nil {
+// Mutation is a kind of change made to an issue.
+type Mutation string
+
+const (
+	// IssueCreated is when an issue is created.
+	IssueCreated Mutation = "issue_created"
+	// CommentCreated is when a comment is created.
+	CommentCreated Mutation = "comment_created"
+	// CommentEdited is when the body of a comment, including the issue description, is edited.
+	CommentEdited Mutation = "comment_edited"
+	// Reacted is when a reaction to a comment is toggled.
+	Reacted Mutation = "reacted"
+	// StateChanged is when an issue is closed or reopened.
+	StateChanged Mutation = "state_changed"
+	// Renamed is when an issue is renamed.
+	Renamed Mutation = "renamed"
+	// Labeled is when labels are added to or removed from an issue.
+	Labeled Mutation = "labeled"
+	// Referenced is when an issue is referenced from another issue.
+	Referenced Mutation = "referenced"
+	// Related is when relations to other issues are added or removed.
+	Related Mutation = "related"
+	// FieldChanged is when the value of a custom field is changed.
+	FieldChanged Mutation = "field_changed"
+)
+
+// NotifyPolicy reports whether subscribers should be notified of mutation m.
+// Users newly @mentioned in an edited comment are notified regardless.
+type NotifyPolicy func(m Mutation) bool
+
+// eventMutation returns the kind of mutation that results in an event of type et.
+func eventMutation(et issues.EventType) Mutation {
+	switch et {
+	case issues.Closed, issues.Reopened:
+		return StateChanged
+	case issues.Renamed:
+		return Renamed
+	case issues.Labeled, issues.Unlabeled:
+		return Labeled
+	case issues.CrossReferenced:
+		return Referenced
+	case issues.Related, issues.Unrelated:
+		return Related
+	case issues.FieldChanged:
+		return FieldChanged
+	default:
+		return Mutation(et)
+	}
+}
+
+// notify notifies all subscribed users of mutation m that shows up in their Notification Center,
+// if the notify policy allows it. event is the event that triggered the notification, if any.
+func (s *service) notify(ctx context.Context, repo issues.RepoSpec, issueID uint64, m Mutation, htmlURL string, event *issues.Event, actor users.UserSpec, time time.Time) error {
+	if s.notifications == nil || !s.policy(m) {
 		return nil
 	}
 
-	// TODO, THINK: Is this the best place/time?
-	// Get issue from storage for to populate notification fields.
-	var issue issue
-	err := jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, issueID, 0), &issue)
+	nr, err := s.notificationRequest(ctx, repo, issueID, htmlURL, event, actor, time)
 	if err != nil {
 		return err
 	}
 
-	nr := notifications.NotificationRequest{
-		Title:     issue.Title,
-		Icon:      notificationIcon(issue.State),
-		Color:     notificationColor(issue.State),
-		Actor:     actor,
-		UpdatedAt: time,
-		HTMLURL:   htmlURL(repo.URI, issueID, fragment),
-	}
-
 	return s.notifications.Notify(ctx, notifications.RepoSpec(repo), threadType, issueID, nr)
 }
 
-// TODO, THINK: Where should the logic to come up with the URL live?
-//              It's kinda related to the router/URL scheme of issuesapp...
-func htmlURL(repoURI string, issueID uint64, fragment string) string {
-	var htmlURL string
-	// TODO: Find a good way to factor out this logic and provide it to issues/fs in a reasonable way.
-	switch {
-	default:
-		htmlURL = fmt.Sprintf("https://%s/...$issues/%v", repoURI, issueID)
-	case repoURI == "dmitri.shuralyov.com/blog":
-		htmlURL = fmt.Sprintf("https://dmitri.shuralyov.com/blog/%v", issueID)
-	case repoURI == "dmitri.shuralyov.com/idiomatic-go":
-		htmlURL = fmt.Sprintf("https://dmitri.shuralyov.com/idiomatic-go/entries/%v", issueID)
-	case strings.HasPrefix(repoURI, "github.com/shurcooL/"):
-		htmlURL = fmt.Sprintf("https://dmitri.shuralyov.com/issues/%s/%v", repoURI, issueID)
+// notifyNewlyMentioned notifies users that are @mentioned in body after an edit,
+// but weren't mentioned before it. If the notifications service doesn't support
+// notifying specific users, all subscribers are notified, which include the newly
+// mentioned users, since they're subscribed by s.subscribe.
+func (s *service) notifyNewlyMentioned(ctx context.Context, repo issues.RepoSpec, issueID uint64, htmlURL string, actor users.UserSpec, time time.Time, before, after string) error {
+	if s.notifications == nil {
+		return nil
 	}
-	if fragment != "" {
-		htmlURL += "#" + fragment
+
+	mentionedBefore, err := s.mentionedUsers(ctx, repo, issueID, before)
+	if err != nil {
+		return err
+	}
+	mentionedAfter, err := s.mentionedUsers(ctx, repo, issueID, after)
+	if err != nil {
+		return err
+	}
+	mentioned := newlyMentioned(mentionedBefore, mentionedAfter)
+	if len(mentioned) == 0 {
+		return nil
 	}
-	return htmlURL
-}
 
-// TODO: This is display/presentation logic; try to factor it out of the backend service implementation.
-//       (Have it be provided to the service, maybe? Or another way.)
-func notificationIcon(state issues.State) notifications.OcticonID {
-	switch state {
-	case issues.OpenState:
-		return "issue-opened"
-	case issues.ClosedState:
-		return "issue-closed"
-	default:
-		return ""
+	nr, err := s.notificationRequest(ctx, repo, issueID, htmlURL, nil, actor, time)
+	if err != nil {
+		return err
+	}
+
+	if un, ok := s.notifications.(UserNotifier); ok {
+		return un.NotifyUsers(ctx, notifications.RepoSpec(repo), threadType, issueID, nr, mentioned)
+	}
+	err = s.notifications.Subscribe(ctx, notifications.RepoSpec(repo), threadType, issueID, mentioned)
+	if err != nil {
+		return err
 	}
+	return s.notifications.Notify(ctx, notifications.RepoSpec(repo), threadType, issueID, nr)
 }
 
-/* TODO
-func (e event) Octicon() string {
-	switch e.Event.Type {
-	case issues.Reopened:
-		return "octicon-primitive-dot"
-	case issues.Closed:
-		return "octicon-circle-slash"
-	default:
-		return "octicon-primitive-dot"
+func (s *service) notificationRequest(ctx context.Context, repo issues.RepoSpec, issueID uint64, htmlURL string, event *issues.Event, actor users.UserSpec, time time.Time) (notifications.NotificationRequest, error) {
+	// TODO, THINK: Is this the best place/time?
+	// Get issue from storage for to populate notification fields.
+	var issue issue
+	err := jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, issueID, 0), &issue)
+	if err != nil {
+		return notifications.NotificationRequest{}, err
 	}
-}*/
-
-func notificationColor(state issues.State) notifications.RGB {
-	switch state {
-	case issues.OpenState: // Open.
-		return notifications.RGB{R: 0x6c, G: 0xc6, B: 0x44}
-	case issues.ClosedState: // Closed.
-		return notifications.RGB{R: 0xbd, G: 0x2c, B: 0x00}
-	default:
-		return notifications.RGB{}
+	var labels []issues.Label
+	for _, l := range issue.Labels {
+		labels = append(labels, issues.Label{
+			Name:  l.Name,
+			Color: l.Color.RGB(),
+		})
 	}
+
+	title, icon, color := s.presenter.PresentNotification(ctx, repo, issues.Issue{
+		ID:     issueID,
+		State:  issue.State,
+		Title:  issue.Title,
+		Labels: labels,
+	}, event)
+	return notifications.NotificationRequest{
+		Title:     title,
+		Icon:      icon,
+		Color:     color,
+		Actor:     actor,
+		UpdatedAt: time,
+		HTMLURL:   htmlURL,
+	}, nil
 }
@@ -0,0 +1,261 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	eventpkg "github.com/shurcooL/events/event"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/notifications"
+	"github.com/shurcooL/reactions"
+	"github.com/shurcooL/webdavfs/vfsutil"
+	"golang.org/x/net/webdav"
+)
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	ns, es := new(mockNotifications), new(mockEvents)
+	s, err := NewService(webdav.NewMemFS(), ns, es, mockUsers{ID: 1}, &Options{Router: mockRouter{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Comment URLs are used for new comments.
+	ns.requests, *es = nil, nil
+	_, err = s.CreateComment(ctx, repo, 1, issues.Comment{Body: "Comment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkURLs(t, ns, es, "https://example.org/issue/1/comment/1")
+
+	// Event URLs are used for events.
+	ns.requests, *es = nil, nil
+	state := issues.ClosedState
+	_, _, err = s.Edit(ctx, repo, 1, issues.IssueRequest{State: &state})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkURLs(t, ns, es, "https://example.org/issue/1/event/1")
+}
+
+func TestPresenter(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	ns := new(mockNotifications)
+	s, err := NewService(webdav.NewMemFS(), ns, nil, mockUsers{ID: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.(issues.Closer)
+	setState := func(state issues.State, closer interface{}) func() error {
+		return func() error {
+			_, _, err := c.SetState(ctx, repo, 1, state, issues.Close{Closer: closer})
+			return err
+		}
+	}
+
+	// The default presenter varies icon and color by issue state and event type.
+	for _, tc := range []struct {
+		name  string
+		do    func() error
+		icon  notifications.OcticonID
+		color notifications.RGB
+	}{
+		{"created", func() error {
+			_, err := s.Create(ctx, repo, issues.Issue{Title: "Issue"})
+			return err
+		}, "issue-opened", openColor},
+		{"closed by commit", setState(issues.ClosedState, issues.Commit{SHA: "abc123"}), "git-commit", closedColor},
+		{"reopened", setState(issues.OpenState, nil), "issue-reopened", openColor},
+		{"closed by change", setState(issues.ClosedState, issues.Change{HTMLURL: "https://example.com/change/1"}), "git-pull-request", mergedColor},
+		{"commented on closed issue", func() error {
+			_, err := s.CreateComment(ctx, repo, 1, issues.Comment{Body: "Comment"})
+			return err
+		}, "issue-closed", closedColor},
+		{"renamed", func() error {
+			title := "Renamed issue"
+			_, _, err := s.Edit(ctx, repo, 1, issues.IssueRequest{Title: &title})
+			return err
+		}, "pencil", grayColor},
+	} {
+		ns.requests = nil
+		if err := tc.do(); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(ns.requests) != 1 || ns.requests[0].Icon != tc.icon || ns.requests[0].Color != tc.color {
+			t.Errorf("%s: got notifications %+v, want one with icon %q and color %v", tc.name, ns.requests, tc.icon, tc.color)
+		}
+	}
+
+	// Label events, which this service doesn't create, use the color of the label.
+	red := issues.RGB{R: 0xff}
+	_, icon, color := DefaultPresenter{}.PresentNotification(ctx, repo, issues.Issue{State: issues.OpenState}, &issues.Event{Type: issues.Labeled, Label: &issues.Label{Name: "bug", Color: red}})
+	if icon != "tag" || color != notifications.RGB(red) {
+		t.Errorf("got icon %q and color %v for a labeled event, want tag and %v", icon, color, red)
+	}
+
+	// A custom presenter is used.
+	ns = new(mockNotifications)
+	s, err = NewService(webdav.NewMemFS(), ns, nil, mockUsers{ID: 1}, &Options{Presenter: mockPresenter{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Create(ctx, repo, issues.Issue{Title: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := notifications.NotificationRequest{Title: "[open] Issue", Icon: "mock", Color: notifications.RGB{B: 0xff}}
+	if len(ns.requests) != 1 || ns.requests[0].Title != want.Title || ns.requests[0].Icon != want.Icon || ns.requests[0].Color != want.Color {
+		t.Errorf("got notifications %+v, want one with title %q, icon %q and color %v", ns.requests, want.Title, want.Icon, want.Color)
+	}
+}
+
+func TestNotifyPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+	const issueURL = "https://example.com/repo/...$issues/"
+	emoji := reactions.EmojiID("+1")
+	body := "Edited body"
+	closed, title := issues.ClosedState, "Renamed issue"
+
+	// Labels can't be edited in this service, so Labeled isn't tested.
+	for _, tc := range []struct {
+		m    Mutation
+		do   func(s issues.Service) error
+		want []string // HTMLURLs of notifications, relative to issueURL.
+	}{
+		{IssueCreated, func(s issues.Service) error {
+			_, err := s.Create(ctx, repo, issues.Issue{Title: "Issue 3"})
+			return err
+		}, []string{"3"}},
+		{CommentCreated, func(s issues.Service) error {
+			_, err := s.CreateComment(ctx, repo, 1, issues.Comment{Body: "Comment"})
+			return err
+		}, []string{"1#comment-1"}},
+		{CommentEdited, func(s issues.Service) error {
+			_, err := s.CreateComment(ctx, repo, 1, issues.Comment{Body: "Comment"})
+			if err != nil {
+				return err
+			}
+			_, err = s.EditComment(ctx, repo, 1, issues.CommentRequest{ID: 1, Body: &body})
+			return err
+		}, []string{"1#comment-1"}},
+		{Reacted, func(s issues.Service) error {
+			// The issue description links to the issue.
+			_, err := s.EditComment(ctx, repo, 1, issues.CommentRequest{ID: 0, Reaction: &emoji})
+			return err
+		}, []string{"1"}},
+		{StateChanged, func(s issues.Service) error {
+			_, _, err := s.Edit(ctx, repo, 1, issues.IssueRequest{State: &closed})
+			return err
+		}, []string{"1#event-1"}},
+		{Renamed, func(s issues.Service) error {
+			_, _, err := s.Edit(ctx, repo, 1, issues.IssueRequest{Title: &title})
+			return err
+		}, []string{"1#event-1"}},
+		{FieldChanged, func(s issues.Service) error {
+			_, _, err := s.Edit(ctx, repo, 1, issues.IssueRequest{Fields: []issues.Field{{Name: "Estimate", Value: int64(3)}}})
+			return err
+		}, []string{"1#event-1"}},
+		{Referenced, func(s issues.Service) error {
+			_, err := s.CreateComment(ctx, repo, 1, issues.Comment{Body: "See #2."})
+			return err
+		}, []string{"2#event-1"}},
+		{Related, func(s issues.Service) error {
+			_, err := s.(issues.Relater).AddRelation(ctx, repo, 1, issues.Relation{Type: issues.Blocks, Repo: repo, ID: 2})
+			return err
+		}, []string{"1#event-1", "2#event-1"}},
+	} {
+		for _, allowed := range []bool{true, false} {
+			root := webdav.NewMemFS()
+			if err := vfsutil.MkdirAll(ctx, root, repo.URI, 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := jsonEncodeFile(ctx, root, fieldDefsPath(repo), []fieldDef{{Name: "Estimate", Type: issues.IntField}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := new(mockNotifications)
+			m := tc.m
+			policy := func(x Mutation) bool { return allowed && x == m }
+			s, err := NewService(root, ns, nil, mockUsers{ID: 1}, &Options{Policy: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 2; i++ {
+				_, err := s.Create(ctx, repo, issues.Issue{Title: fmt.Sprint("Issue ", i)})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			ns.requests = nil
+
+			if err := tc.do(s); err != nil {
+				t.Fatalf("%s: %v", tc.m, err)
+			}
+			var got, want []string
+			for _, nr := range ns.requests {
+				got = append(got, nr.HTMLURL)
+			}
+			if allowed {
+				for _, u := range tc.want {
+					want = append(want, issueURL+u)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s (allowed: %v): got notifications with URLs %q, want %q", tc.m, allowed, got, want)
+			}
+		}
+	}
+}
+
+// checkURLs checks that there's one notification and one logged event,
+// and that both link to want.
+func checkURLs(t *testing.T, ns *mockNotifications, es *mockEvents, want string) {
+	t.Helper()
+	if len(ns.requests) != 1 || ns.requests[0].HTMLURL != want {
+		t.Errorf("got notifications %+v, want one with HTMLURL %q", ns.requests, want)
+	}
+	if len(*es) != 1 {
+		t.Fatalf("got logged events %+v, want one", *es)
+	}
+	var got string
+	switch p := (*es)[0].Payload.(type) {
+	case eventpkg.Issue:
+		got = p.IssueHTMLURL
+	case eventpkg.IssueComment:
+		got = p.CommentHTMLURL
+	}
+	if got != want {
+		t.Errorf("got logged event URL %q, want %q", got, want)
+	}
+}
+
+// mockRouter is a Router with URLs that are unlike those of DefaultRouter.
+type mockRouter struct{}
+
+func (mockRouter) IssueURL(_ context.Context, _ issues.RepoSpec, issueID uint64) string {
+	return fmt.Sprintf("https://example.org/issue/%d", issueID)
+}
+
+func (mockRouter) IssueCommentURL(_ context.Context, _ issues.RepoSpec, issueID, commentID uint64) string {
+	return fmt.Sprintf("https://example.org/issue/%d/comment/%d", issueID, commentID)
+}
+
+func (mockRouter) IssueEventURL(_ context.Context, _ issues.RepoSpec, issueID, eventID uint64) string {
+	return fmt.Sprintf("https://example.org/issue/%d/event/%d", issueID, eventID)
+}
+
+// mockPresenter is a Presenter that prefixes titles with the issue state.
+type mockPresenter struct{}
+
+func (mockPresenter) PresentNotification(_ context.Context, _ issues.RepoSpec, issue issues.Issue, _ *issues.Event) (string, notifications.OcticonID, notifications.RGB) {
+	return fmt.Sprintf("[%s] %s", issue.State, issue.Title), "mock", notifications.RGB{B: 0xff}
+}
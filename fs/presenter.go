@@ -0,0 +1,74 @@
+package fs
+
+import (
+	"context"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/notifications"
+)
+
+// Presenter determines how notifications about issues are presented.
+type Presenter interface {
+	// PresentNotification returns the title, icon and color of a notification about issue.
+	// event is the event that triggered the notification, or nil if it was triggered
+	// by a new issue or comment. issue reflects the state after event.
+	PresentNotification(ctx context.Context, repo issues.RepoSpec, issue issues.Issue, event *issues.Event) (title string, icon notifications.OcticonID, color notifications.RGB)
+}
+
+// DefaultPresenter is the default Presenter. It uses the issue title,
+// and icons and colors matching those used on GitHub.
+type DefaultPresenter struct{}
+
+var (
+	openColor   = notifications.RGB{R: 0x6c, G: 0xc6, B: 0x44}
+	closedColor = notifications.RGB{R: 0xbd, G: 0x2c, B: 0x00}
+	mergedColor = notifications.RGB{R: 0x6e, G: 0x54, B: 0x94}
+	grayColor   = notifications.RGB{R: 0x76, G: 0x76, B: 0x76}
+)
+
+// PresentNotification implements Presenter.
+func (DefaultPresenter) PresentNotification(_ context.Context, _ issues.RepoSpec, issue issues.Issue, event *issues.Event) (string, notifications.OcticonID, notifications.RGB) {
+	if event == nil {
+		icon, color := stateIcon(issue.State)
+		return issue.Title, icon, color
+	}
+	switch event.Type {
+	case issues.Closed:
+		switch event.Close.Closer.(type) {
+		case issues.Change:
+			return issue.Title, "git-pull-request", mergedColor
+		case issues.Commit:
+			return issue.Title, "git-commit", closedColor
+		default:
+			return issue.Title, "issue-closed", closedColor
+		}
+	case issues.Reopened:
+		return issue.Title, "issue-reopened", openColor
+	case issues.Renamed:
+		return issue.Title, "pencil", grayColor
+	case issues.Labeled, issues.Unlabeled:
+		if event.Label != nil {
+			return issue.Title, "tag", notifications.RGB(event.Label.Color)
+		}
+		return issue.Title, "tag", grayColor
+	case issues.CrossReferenced:
+		return issue.Title, "cross-reference", grayColor
+	case issues.Related, issues.Unrelated:
+		return issue.Title, "link", grayColor
+	default:
+		icon, color := stateIcon(issue.State)
+		return issue.Title, icon, color
+	}
+}
+
+// stateIcon returns the icon and color for an issue in the given state.
+func stateIcon(state issues.State) (notifications.OcticonID, notifications.RGB) {
+	switch state {
+	case issues.OpenState:
+		return "issue-opened", openColor
+	case issues.ClosedState:
+		return "issue-closed", closedColor
+	default:
+		return "", notifications.RGB{}
+	}
+}
@@ -0,0 +1,147 @@
+package fs
+
+import (
+	"context"
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// reference is a reference to an issue, like "#12" or "owner/repo#12".
+type reference struct {
+	Repo string // Repo is the referenced repository as written, or empty for the same repository.
+	ID   uint64
+}
+
+// referenceRE matches an issue reference. The character before it, if any,
+// is matched too, so that URL fragments and the like aren't mistaken for references.
+var referenceRE = regexp.MustCompile(`(^|[^A-Za-z0-9_.\-/#&\x60])((?:[A-Za-z0-9_.\-]+/)*[A-Za-z0-9_.\-]+)?#([0-9]+)\b`)
+
+// references returns unique issue references in Markdown body, in order of appearance.
+// References inside code blocks and inline code spans are ignored.
+func references(body string) []reference {
+	var (
+		refs []reference
+		seen = make(map[reference]bool)
+	)
+	for _, line := range proseLines(body) {
+		for _, m := range referenceRE.FindAllStringSubmatch(line, -1) {
+			id, err := strconv.ParseUint(m[3], 10, 64)
+			if err != nil || id == 0 {
+				continue
+			}
+			ref := reference{Repo: m[2], ID: id}
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolveReference resolves ref made from repo to an existing issue.
+// A reference to another repository is either its full URI, like "example.com/foo#1",
+// or a path relative to the host of repo, like "owner/repo#1" from "github.com/owner/other".
+func (s *service) resolveReference(ctx context.Context, repo issues.RepoSpec, ref reference) (issues.RepoSpec, bool) {
+	candidates := []issues.RepoSpec{repo}
+	if ref.Repo != "" {
+		candidates = []issues.RepoSpec{{URI: ref.Repo}}
+		if i := strings.IndexByte(repo.URI, '/'); i != -1 {
+			candidates = append(candidates, issues.RepoSpec{URI: path.Join(repo.URI[:i], ref.Repo)})
+		}
+	}
+	for _, r := range candidates {
+		if path.Clean("/"+r.URI) != "/"+r.URI {
+			continue
+		}
+		if _, err := s.fs.Stat(ctx, issueCommentPath(r, ref.ID, 0)); err == nil {
+			return r, true
+		}
+	}
+	return issues.RepoSpec{}, false
+}
+
+// crossReference records a CrossReferenced event on each issue referenced in body
+// of a comment at htmlURL in the specified issue, and notifies its subscribers.
+// References that are also in before, the body prior to an edit, are skipped.
+// Errors are logged rather than returned, since they shouldn't fail the operation
+// that created the comment.
+func (s *service) crossReference(ctx context.Context, repo issues.RepoSpec, issueID uint64, htmlURL string, actor users.UserSpec, createdAt time.Time, before, body string) {
+	refs := references(body)
+	if len(refs) == 0 {
+		return
+	}
+	old := make(map[reference]bool)
+	for _, ref := range references(before) {
+		old[ref] = true
+	}
+
+	var issue issue
+	err := jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, issueID, 0), &issue)
+	if err != nil {
+		log.Println("service.crossReference: failed to read issue:", err)
+		return
+	}
+
+	done := make(map[issues.RepoSpec]map[uint64]bool)
+	for _, ref := range refs {
+		if old[ref] {
+			continue
+		}
+		target, ok := s.resolveReference(ctx, repo, ref)
+		if !ok || (target == repo && ref.ID == issueID) || done[target][ref.ID] {
+			continue
+		}
+		if done[target] == nil {
+			done[target] = make(map[uint64]bool)
+		}
+		done[target][ref.ID] = true
+
+		event := event{
+			Actor:     fromUserSpec(actor),
+			CreatedAt: createdAt,
+			Type:      issues.CrossReferenced,
+			CrossReference: fromCrossReference(&issues.CrossReference{
+				Source: issues.IssueRef{
+					Repo:    repo,
+					ID:      issueID,
+					Title:   issue.Title,
+					HTMLURL: htmlURL,
+				},
+			}),
+		}
+
+		// Commit to storage.
+		eventID, err := nextID(ctx, s.fs, issueEventsDir(target, ref.ID))
+		if err != nil {
+			log.Println("service.crossReference: failed to nextID:", err)
+			continue
+		}
+		err = jsonEncodeFile(ctx, s.fs, issueEventPath(target, ref.ID, eventID), event)
+		if err != nil {
+			log.Println("service.crossReference: failed to jsonEncodeFile:", err)
+			continue
+		}
+
+		// Notify subscribed users.
+		e := issues.Event{
+			ID:             eventID,
+			Actor:          s.user(ctx, actor),
+			CreatedAt:      event.CreatedAt,
+			Type:           event.Type,
+			CrossReference: event.CrossReference.CrossReference(),
+		}
+		err = s.notify(ctx, target, ref.ID, Referenced, s.rtr.IssueEventURL(ctx, target, ref.ID, eventID), &e, actor, createdAt)
+		if err != nil {
+			log.Println("service.crossReference: failed to s.notify:", err)
+		}
+	}
+}
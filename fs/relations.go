@@ -0,0 +1,354 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// AddRelation implements issues.Relater.
+func (s *service) AddRelation(ctx context.Context, repo issues.RepoSpec, id uint64, r issues.Relation) ([]issues.Event, error) {
+	currentUser, err := s.users.GetAuthenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if currentUser.ID == 0 {
+		return nil, os.ErrPermission
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	if r.Repo == repo && r.ID == id {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, errors.New("issue can't be related to itself")
+	}
+
+	s.fsMu.Lock()
+	defer s.fsMu.Unlock()
+
+	// Get from storage.
+	var target issue
+	err = jsonDecodeFile(ctx, s.fs, issueCommentPath(r.Repo, r.ID, 0), &target)
+	if err != nil {
+		return nil, err
+	}
+	var issue issue
+	err = jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, id, 0), &issue)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authorization check. Relations are written to both issues.
+	if err := canEdit(currentUser, issue.Author); err != nil {
+		return nil, err
+	}
+	if err := canEdit(currentUser, target.Author); err != nil {
+		return nil, err
+	}
+
+	rels, err := s.readRelations(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range rels {
+		if rel == fromRelation(r) {
+			// Already related, nothing to do.
+			return nil, nil
+		}
+	}
+	if cycle, err := s.wouldCycle(ctx, repo, id, r); err != nil {
+		return nil, err
+	} else if cycle {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, errors.New("relation would create a cycle")
+	}
+	targetRels, err := s.readRelations(ctx, r.Repo, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	inverse := issues.Relation{Type: r.Type.Inverse(), Repo: repo, ID: id}
+
+	// Commit to storage.
+	err = jsonEncodeFile(ctx, s.fs, issueRelationsPath(repo, id), append(rels, fromRelation(r)))
+	if err != nil {
+		return nil, err
+	}
+	err = jsonEncodeFile(ctx, s.fs, issueRelationsPath(r.Repo, r.ID), append(targetRels, fromRelation(inverse)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create events and commit to storage.
+	createdAt := time.Now().UTC()
+	actor := currentUser.UserSpec
+	rel, inv := fromRelation(r), fromRelation(inverse)
+	events := []eventAt{
+		{Repo: repo, IssueID: id, event: event{Actor: fromUserSpec(actor), CreatedAt: createdAt, Type: issues.Related, Relation: &rel}},
+		{Repo: r.Repo, IssueID: r.ID, event: event{Actor: fromUserSpec(actor), CreatedAt: createdAt, Type: issues.Related, Relation: &inv}},
+	}
+	if r.Type == issues.DuplicateOf && issue.State == issues.OpenState {
+		// Close as a duplicate of target.
+		issue.State = issues.ClosedState
+		err = jsonEncodeFile(ctx, s.fs, issueCommentPath(repo, id, 0), issue)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, eventAt{Repo: repo, IssueID: id, event: event{
+			Actor:     fromUserSpec(actor),
+			CreatedAt: createdAt,
+			Type:      issues.Closed,
+			Close: fromClose(issues.Close{
+				Closer: issues.IssueRef{
+					Repo:    r.Repo,
+					ID:      r.ID,
+					Title:   target.Title,
+					HTMLURL: s.rtr.IssueURL(ctx, r.Repo, r.ID),
+				},
+			}),
+		}})
+	}
+	return s.createEvents(ctx, repo, id, events, currentUser)
+}
+
+// RemoveRelation implements issues.Relater.
+func (s *service) RemoveRelation(ctx context.Context, repo issues.RepoSpec, id uint64, r issues.Relation) ([]issues.Event, error) {
+	currentUser, err := s.users.GetAuthenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if currentUser.ID == 0 {
+		return nil, os.ErrPermission
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.fsMu.Lock()
+	defer s.fsMu.Unlock()
+
+	// Get from storage.
+	var target issue
+	err = jsonDecodeFile(ctx, s.fs, issueCommentPath(r.Repo, r.ID, 0), &target)
+	if err != nil {
+		return nil, err
+	}
+	var issue issue
+	err = jsonDecodeFile(ctx, s.fs, issueCommentPath(repo, id, 0), &issue)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authorization check. Relations are removed from both issues.
+	if err := canEdit(currentUser, issue.Author); err != nil {
+		return nil, err
+	}
+	if err := canEdit(currentUser, target.Author); err != nil {
+		return nil, err
+	}
+
+	rels, err := s.readRelations(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	rels, ok := removeRelation(rels, fromRelation(r))
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	inverse := issues.Relation{Type: r.Type.Inverse(), Repo: repo, ID: id}
+	targetRels, err := s.readRelations(ctx, r.Repo, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	targetRels, targetOK := removeRelation(targetRels, fromRelation(inverse))
+
+	// Commit to storage.
+	err = jsonEncodeFile(ctx, s.fs, issueRelationsPath(repo, id), rels)
+	if err != nil {
+		return nil, err
+	}
+	if targetOK {
+		err = jsonEncodeFile(ctx, s.fs, issueRelationsPath(r.Repo, r.ID), targetRels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create events and commit to storage.
+	createdAt := time.Now().UTC()
+	actor := currentUser.UserSpec
+	rel, inv := fromRelation(r), fromRelation(inverse)
+	events := []eventAt{
+		{Repo: repo, IssueID: id, event: event{Actor: fromUserSpec(actor), CreatedAt: createdAt, Type: issues.Unrelated, Relation: &rel}},
+	}
+	if targetOK {
+		events = append(events, eventAt{Repo: r.Repo, IssueID: r.ID, event: event{Actor: fromUserSpec(actor), CreatedAt: createdAt, Type: issues.Unrelated, Relation: &inv}})
+	}
+	return s.createEvents(ctx, repo, id, events, currentUser)
+}
+
+// ListRelations implements issues.Relater.
+func (s *service) ListRelations(ctx context.Context, repo issues.RepoSpec, id uint64) ([]issues.Relation, error) {
+	s.fsMu.RLock()
+	defer s.fsMu.RUnlock()
+
+	// Make sure the issue exists.
+	if _, err := s.fs.Stat(ctx, issueCommentPath(repo, id, 0)); err != nil {
+		return nil, err
+	}
+
+	rels, err := s.readRelations(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	var relations []issues.Relation
+	for _, r := range rels {
+		relations = append(relations, r.Relation())
+	}
+	return relations, nil
+}
+
+// readRelations reads relations of the specified issue.
+// It returns no relations if the issue has none.
+func (s *service) readRelations(ctx context.Context, repo issues.RepoSpec, issueID uint64) ([]relation, error) {
+	var rels []relation
+	err := jsonDecodeFile(ctx, s.fs, issueRelationsPath(repo, issueID), &rels)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return rels, err
+}
+
+// removeRelation removes r from rels, reporting whether it was there.
+func removeRelation(rels []relation, r relation) ([]relation, bool) {
+	for i := range rels {
+		if rels[i] == r {
+			return append(rels[:i:i], rels[i+1:]...), true
+		}
+	}
+	return rels, false
+}
+
+// issueKey identifies an issue across repositories.
+type issueKey struct {
+	Repo issues.RepoSpec
+	ID   uint64
+}
+
+// wouldCycle reports whether adding relation r to the specified issue would create
+// a cycle of Blocks or ParentOf relations. Other relation types never form cycles.
+func (s *service) wouldCycle(ctx context.Context, repo issues.RepoSpec, id uint64, r issues.Relation) (bool, error) {
+	// Normalize to an edge in the forward direction, from → to.
+	from, to := issueKey{repo, id}, issueKey{r.Repo, r.ID}
+	forward := r.Type
+	switch r.Type {
+	case issues.Blocks, issues.ParentOf:
+	case issues.BlockedBy, issues.ChildOf:
+		from, to = to, from
+		forward = r.Type.Inverse()
+	default:
+		return false, nil
+	}
+
+	// The edge creates a cycle if from is already reachable from to.
+	seen := map[issueKey]bool{to: true}
+	stack := []issueKey{to}
+	for len(stack) > 0 {
+		k := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if k == from {
+			return true, nil
+		}
+		rels, err := s.readRelations(ctx, k.Repo, k.ID)
+		if err != nil {
+			return false, err
+		}
+		for _, rel := range rels {
+			next := issueKey{issues.RepoSpec{URI: rel.RepoURI}, rel.ID}
+			if rel.Type != forward || seen[next] {
+				continue
+			}
+			seen[next] = true
+			stack = append(stack, next)
+		}
+	}
+	return false, nil
+}
+
+// eventAt is an event to be created on the specified issue.
+type eventAt struct {
+	Repo    issues.RepoSpec
+	IssueID uint64
+	event
+}
+
+// createEvents commits events to storage, then notifies subscribers of each and logs it.
+// It returns the events that were created on the specified issue.
+func (s *service) createEvents(ctx context.Context, repo issues.RepoSpec, id uint64, events []eventAt, currentUser users.User) ([]issues.Event, error) {
+	created := make([]issues.Event, len(events))
+	for i, e := range events {
+		eventID, err := nextID(ctx, s.fs, issueEventsDir(e.Repo, e.IssueID))
+		if err != nil {
+			return nil, err
+		}
+		err = jsonEncodeFile(ctx, s.fs, issueEventPath(e.Repo, e.IssueID, eventID), e.event)
+		if err != nil {
+			return nil, err
+		}
+		created[i] = issues.Event{
+			ID:        eventID,
+			Actor:     currentUser,
+			CreatedAt: e.CreatedAt,
+			Type:      e.Type,
+			Close:     e.Close.Close(),
+			Relation:  relationPtr(e.Relation),
+		}
+	}
+
+	// Subscribe interested users.
+	err := s.subscribe(ctx, repo, id, currentUser.UserSpec, "")
+	if err != nil {
+		log.Println("service.createEvents: failed to s.subscribe:", err)
+	}
+
+	var result []issues.Event
+	for i, e := range events {
+		eventURL := s.rtr.IssueEventURL(ctx, e.Repo, e.IssueID, created[i].ID)
+
+		// Notify subscribed users.
+		err = s.notify(ctx, e.Repo, e.IssueID, eventMutation(e.Type), eventURL, &created[i], currentUser.UserSpec, e.CreatedAt)
+		if err != nil {
+			log.Println("service.createEvents: failed to s.notify:", err)
+		}
+
+		// Log event.
+		var issue issue
+		err = jsonDecodeFile(ctx, s.fs, issueCommentPath(e.Repo, e.IssueID, 0), &issue)
+		if err == nil {
+			err = s.logIssue(ctx, e.Repo, e.IssueID, eventURL, issue, currentUser, string(e.Type), e.CreatedAt)
+		}
+		if err != nil {
+			log.Println("service.createEvents: failed to s.logIssue:", err)
+		}
+
+		if e.Repo == repo && e.IssueID == id {
+			result = append(result, created[i])
+		}
+	}
+	return result, nil
+}
+
+// relationPtr converts an optional on-disk relation to *issues.Relation.
+func relationPtr(r *relation) *issues.Relation {
+	if r == nil {
+		return nil
+	}
+	rel := r.Relation()
+	return &rel
+}
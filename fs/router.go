@@ -0,0 +1,40 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shurcooL/issues"
+)
+
+// Router provides URLs for issues, comments and events. It's used to link
+// to them from notifications and events.
+type Router interface {
+	// IssueURL returns the URL of the specified issue.
+	IssueURL(ctx context.Context, repo issues.RepoSpec, issueID uint64) string
+
+	// IssueCommentURL returns the URL of the specified issue comment.
+	IssueCommentURL(ctx context.Context, repo issues.RepoSpec, issueID, commentID uint64) string
+
+	// IssueEventURL returns the URL of the specified issue event.
+	IssueEventURL(ctx context.Context, repo issues.RepoSpec, issueID, eventID uint64) string
+}
+
+// DefaultRouter is the default Router. It links to issues
+// served by issuesapp at "https://{{.RepoURI}}/...$issues".
+type DefaultRouter struct{}
+
+// IssueURL implements Router.
+func (DefaultRouter) IssueURL(_ context.Context, repo issues.RepoSpec, issueID uint64) string {
+	return fmt.Sprintf("https://%s/...$issues/%d", repo.URI, issueID)
+}
+
+// IssueCommentURL implements Router.
+func (DefaultRouter) IssueCommentURL(_ context.Context, repo issues.RepoSpec, issueID, commentID uint64) string {
+	return fmt.Sprintf("https://%s/...$issues/%d#comment-%d", repo.URI, issueID, commentID)
+}
+
+// IssueEventURL implements Router.
+func (DefaultRouter) IssueEventURL(_ context.Context, repo issues.RepoSpec, issueID, eventID uint64) string {
+	return fmt.Sprintf("https://%s/...$issues/%d#event-%d", repo.URI, issueID, eventID)
+}
@@ -50,13 +50,32 @@ type issue struct {
 	State  issues.State
 	Title  string
 	Labels []label `json:",omitempty"`
+	Fields []field `json:",omitempty"`
 	comment
 }
 
 // label is an on-disk representation of issues.Label.
+// Description is only set in the label catalog of a repo.
 type label struct {
-	Name  string
-	Color rgb
+	Name        string
+	Color       rgb
+	Description string `json:",omitempty"`
+}
+
+func fromLabel(l issues.Label) label {
+	return label{
+		Name:        l.Name,
+		Color:       fromRGB(l.Color),
+		Description: l.Description,
+	}
+}
+
+func (l label) Label() issues.Label {
+	return issues.Label{
+		Name:        l.Name,
+		Color:       l.Color.RGB(),
+		Description: l.Description,
+	}
 }
 
 // comment is an on-disk representation of issues.Comment.
@@ -87,18 +106,22 @@ type event struct {
 	Close     *closeDisk     `json:",omitempty"`
 	Rename    *issues.Rename `json:",omitempty"`
 	Label     *label         `json:",omitempty"`
+
+	CrossReference *crossReference `json:",omitempty"`
+	Relation       *relation       `json:",omitempty"`
+	FieldChange    *fieldChange    `json:",omitempty"`
 }
 
 // closeDisk is an on-disk representation of issues.Close.
 // Nil issues.Close.Closer is represented by nil *closeDisk.
 type closeDisk struct {
-	Closer interface{} // issues.Change, issues.Commit.
+	Closer interface{} // issues.Change, issues.Commit, issues.IssueRef.
 }
 
 func (c closeDisk) MarshalJSON() ([]byte, error) {
 	var v struct {
-		Type   string      // "change", "commit".
-		Closer interface{} // change, commit.
+		Type   string      // "change", "commit", "issue".
+		Closer interface{} // change, commit, issueRef.
 	}
 	switch p := c.Closer.(type) {
 	case issues.Change:
@@ -107,6 +130,9 @@ func (c closeDisk) MarshalJSON() ([]byte, error) {
 	case issues.Commit:
 		v.Type = "commit"
 		v.Closer = fromCommit(p)
+	case issues.IssueRef:
+		v.Type = "issue"
+		v.Closer = fromIssueRef(p)
 	default:
 		return nil, fmt.Errorf("closeDisk.MarshalJSON: unsupported Closer type %T", c.Closer)
 	}
@@ -119,8 +145,8 @@ func (c *closeDisk) UnmarshalJSON(b []byte) error {
 		return nil
 	}
 	var v struct {
-		Type   string          // "change", "commit".
-		Closer json.RawMessage // change, commit.
+		Type   string          // "change", "commit", "issue".
+		Closer json.RawMessage // change, commit, issueRef.
 	}
 	err := json.Unmarshal(b, &v)
 	if err != nil {
@@ -142,6 +168,13 @@ func (c *closeDisk) UnmarshalJSON(b []byte) error {
 			return err
 		}
 		c.Closer = p.Commit()
+	case "issue":
+		var p issueRef
+		err := json.Unmarshal(v.Closer, &p)
+		if err != nil {
+			return err
+		}
+		c.Closer = p.IssueRef()
 	default:
 		return fmt.Errorf("closeDisk.UnmarshalJSON: unsupported Closer type %q", v.Type)
 	}
@@ -193,6 +226,224 @@ func (c commit) Commit() issues.Commit {
 	return issues.Commit(c)
 }
 
+// crossReference is an on-disk representation of issues.CrossReference.
+type crossReference struct {
+	Source interface{} // issues.IssueRef, issues.Change, issues.Commit.
+}
+
+func (c crossReference) MarshalJSON() ([]byte, error) {
+	var v struct {
+		Type   string      // "issue", "change", "commit".
+		Source interface{} // issueRef, change, commit.
+	}
+	switch p := c.Source.(type) {
+	case issues.IssueRef:
+		v.Type = "issue"
+		v.Source = fromIssueRef(p)
+	case issues.Change:
+		v.Type = "change"
+		v.Source = fromChange(p)
+	case issues.Commit:
+		v.Type = "commit"
+		v.Source = fromCommit(p)
+	default:
+		return nil, fmt.Errorf("crossReference.MarshalJSON: unsupported Source type %T", c.Source)
+	}
+	return json.Marshal(v)
+}
+
+func (c *crossReference) UnmarshalJSON(b []byte) error {
+	// Ignore null, like in the main JSON package.
+	if string(b) == "null" {
+		return nil
+	}
+	var v struct {
+		Type   string          // "issue", "change", "commit".
+		Source json.RawMessage // issueRef, change, commit.
+	}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	*c = crossReference{}
+	switch v.Type {
+	case "issue":
+		var p issueRef
+		err := json.Unmarshal(v.Source, &p)
+		if err != nil {
+			return err
+		}
+		c.Source = p.IssueRef()
+	case "change":
+		var p change
+		err := json.Unmarshal(v.Source, &p)
+		if err != nil {
+			return err
+		}
+		c.Source = p.Change()
+	case "commit":
+		var p commit
+		err := json.Unmarshal(v.Source, &p)
+		if err != nil {
+			return err
+		}
+		c.Source = p.Commit()
+	default:
+		return fmt.Errorf("crossReference.UnmarshalJSON: unsupported Source type %q", v.Type)
+	}
+	return nil
+}
+
+func fromCrossReference(c *issues.CrossReference) *crossReference {
+	if c == nil {
+		return nil
+	}
+	return (*crossReference)(c)
+}
+
+func (c *crossReference) CrossReference() *issues.CrossReference {
+	return (*issues.CrossReference)(c)
+}
+
+// issueRef is an on-disk representation of issues.IssueRef.
+type issueRef struct {
+	RepoURI string
+	ID      uint64
+	Title   string
+	HTMLURL string
+}
+
+func fromIssueRef(r issues.IssueRef) issueRef {
+	return issueRef{RepoURI: r.Repo.URI, ID: r.ID, Title: r.Title, HTMLURL: r.HTMLURL}
+}
+
+func (r issueRef) IssueRef() issues.IssueRef {
+	return issues.IssueRef{Repo: issues.RepoSpec{URI: r.RepoURI}, ID: r.ID, Title: r.Title, HTMLURL: r.HTMLURL}
+}
+
+// relation is an on-disk representation of issues.Relation.
+type relation struct {
+	Type    issues.RelationType
+	RepoURI string
+	ID      uint64
+}
+
+func fromRelation(r issues.Relation) relation {
+	return relation{Type: r.Type, RepoURI: r.Repo.URI, ID: r.ID}
+}
+
+func (r relation) Relation() issues.Relation {
+	return issues.Relation{Type: r.Type, Repo: issues.RepoSpec{URI: r.RepoURI}, ID: r.ID}
+}
+
+// template is an on-disk representation of issues.Template.
+// Its name is the name of the file it's stored in.
+type template struct {
+	About       string          `json:",omitempty"`
+	TitlePrefix string          `json:",omitempty"`
+	Body        string          `json:",omitempty"`
+	Labels      []label         `json:",omitempty"`
+	Fields      []templateField `json:",omitempty"`
+}
+
+type templateField struct {
+	Name     string
+	Required bool `json:",omitempty"`
+}
+
+func (t template) Template(name string) issues.Template {
+	var labels []issues.Label
+	for _, l := range t.Labels {
+		labels = append(labels, issues.Label{
+			Name:  l.Name,
+			Color: l.Color.RGB(),
+		})
+	}
+	var fields []issues.TemplateField
+	for _, f := range t.Fields {
+		fields = append(fields, issues.TemplateField(f))
+	}
+	return issues.Template{
+		Name:        name,
+		About:       t.About,
+		TitlePrefix: t.TitlePrefix,
+		Body:        t.Body,
+		Labels:      labels,
+		Fields:      fields,
+	}
+}
+
+// fieldDef is an on-disk representation of issues.FieldDef.
+type fieldDef struct {
+	Name     string
+	Type     issues.FieldType
+	Options  []string `json:",omitempty"`
+	ReadOnly bool     `json:",omitempty"`
+}
+
+// field is an on-disk representation of issues.Field.
+// Exactly one of the value fields is set.
+type field struct {
+	Name   string
+	String *string    `json:",omitempty"` // Enum and string fields.
+	Int    *int64     `json:",omitempty"`
+	Date   *time.Time `json:",omitempty"`
+	User   *userSpec  `json:",omitempty"`
+}
+
+// fromField converts f to its on-disk representation.
+// f.Value must be set, and valid as checked by issues.FieldDef.Validate.
+func fromField(f issues.Field) field {
+	d := field{Name: f.Name}
+	switch v := f.Value.(type) {
+	case string:
+		d.String = &v
+	case int64:
+		d.Int = &v
+	case time.Time:
+		v = v.UTC()
+		d.Date = &v
+	case users.UserSpec:
+		u := fromUserSpec(v)
+		d.User = &u
+	}
+	return d
+}
+
+func (f field) Field() issues.Field {
+	switch {
+	case f.String != nil:
+		return issues.Field{Name: f.Name, Value: *f.String}
+	case f.Int != nil:
+		return issues.Field{Name: f.Name, Value: *f.Int}
+	case f.Date != nil:
+		return issues.Field{Name: f.Name, Value: *f.Date}
+	case f.User != nil:
+		return issues.Field{Name: f.Name, Value: f.User.UserSpec()}
+	default:
+		return issues.Field{Name: f.Name}
+	}
+}
+
+// fieldChange is an on-disk representation of issues.FieldChange.
+// Nil From or To represent an unset field.
+type fieldChange struct {
+	Name string
+	From *field `json:",omitempty"`
+	To   *field `json:",omitempty"`
+}
+
+func (c fieldChange) FieldChange() *issues.FieldChange {
+	fc := &issues.FieldChange{Name: c.Name}
+	if c.From != nil {
+		fc.From = c.From.Field().Value
+	}
+	if c.To != nil {
+		fc.To = c.To.Field().Value
+	}
+	return fc
+}
+
 // Tree layout:
 //
 // 	root
@@ -203,12 +454,26 @@ func (c commit) Commit() issues.Commit {
 // 	            │   ├── 0 - encoded issue
 // 	            │   ├── 1 - encoded comment
 // 	            │   ├── 2
+// 	            │   ├── relations - encoded relations
 // 	            │   └── events
 // 	            │       ├── 1 - encoded event
 // 	            │       └── 2
 // 	            └── 2
 // 	                ├── 0
 // 	                └── events
+//
+// Custom field definitions and the label catalog of a repo, if any, are encoded
+// in issue-fields and issue-labels files next to the issues dir.
+// Issue templates of a repo, if any, are encoded by their name:
+//
+// 	root
+// 	└── domain.com
+// 	    └── path
+// 	        ├── issue-fields - encoded field definitions
+// 	        ├── issue-labels - encoded label catalog
+// 	        └── issue-templates
+// 	            ├── bug - encoded template
+// 	            └── feature
 
 func (s *service) createNamespace(ctx context.Context, repo issues.RepoSpec) error {
 	if path.Clean("/"+repo.URI) != "/"+repo.URI {
@@ -225,10 +490,34 @@ func issuesDir(repo issues.RepoSpec) string {
 	return path.Join(repo.URI, "issues")
 }
 
+// fieldDefsPath is '/'-separated path to custom field definitions of a repo.
+func fieldDefsPath(repo issues.RepoSpec) string {
+	return path.Join(repo.URI, "issue-fields")
+}
+
+// labelsPath is '/'-separated path to the label catalog of a repo.
+func labelsPath(repo issues.RepoSpec) string {
+	return path.Join(repo.URI, "issue-labels")
+}
+
+// templatesDir is '/'-separated path to issue templates dir.
+func templatesDir(repo issues.RepoSpec) string {
+	return path.Join(repo.URI, "issue-templates")
+}
+
+func templatePath(repo issues.RepoSpec, name string) string {
+	return path.Join(repo.URI, "issue-templates", name)
+}
+
 func issueDir(repo issues.RepoSpec, issueID uint64) string {
 	return path.Join(repo.URI, "issues", formatUint64(issueID))
 }
 
+// issueRelationsPath is '/'-separated path to the relations of an issue.
+func issueRelationsPath(repo issues.RepoSpec, issueID uint64) string {
+	return path.Join(repo.URI, "issues", formatUint64(issueID), "relations")
+}
+
 func issueCommentPath(repo issues.RepoSpec, issueID, commentID uint64) string {
 	return path.Join(repo.URI, "issues", formatUint64(issueID), formatUint64(commentID))
 }
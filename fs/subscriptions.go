@@ -0,0 +1,110 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/notifications"
+	"github.com/shurcooL/users"
+)
+
+// SubscriptionManager is an optional interface that the notifications.ExternalService given to NewService
+// can implement to let users unsubscribe from threads, and to list subscribers of a thread.
+// Without it, users can subscribe, but Unsubscribe and ListSubscribers return an error.
+type SubscriptionManager interface {
+	// Unsubscribe unsubscribes subscribers from the specified thread.
+	// If threadType and threadID are zero, subscribers stop watching the entire repo.
+	Unsubscribe(ctx context.Context, repo notifications.RepoSpec, threadType string, threadID uint64, subscribers []users.UserSpec) error
+	// ListSubscribers lists users subscribed to the specified thread.
+	// If threadType and threadID are zero, users watching the entire repo are listed.
+	ListSubscribers(ctx context.Context, repo notifications.RepoSpec, threadType string, threadID uint64) ([]users.UserSpec, error)
+}
+
+// subscriptionThread returns the notifications thread for subscriptions
+// to the specified issue id. If id is 0, it's the thread for watching the entire repo.
+func subscriptionThread(id uint64) (string, uint64) {
+	if id == 0 {
+		return "", 0
+	}
+	return threadType, id
+}
+
+var errSubscriptionsUnsupported = errors.New("subscriptions are not supported by notifications service")
+
+// Subscribe implements issues.Subscriber.
+func (s *service) Subscribe(ctx context.Context, repo issues.RepoSpec, id uint64) error {
+	currentUser, err := s.subscriptionUser(ctx, repo, id)
+	if err != nil {
+		return err
+	}
+	if s.notifications == nil {
+		return errSubscriptionsUnsupported
+	}
+	tt, tid := subscriptionThread(id)
+	return s.notifications.Subscribe(ctx, notifications.RepoSpec(repo), tt, tid, []users.UserSpec{currentUser})
+}
+
+// Unsubscribe implements issues.Subscriber.
+func (s *service) Unsubscribe(ctx context.Context, repo issues.RepoSpec, id uint64) error {
+	currentUser, err := s.subscriptionUser(ctx, repo, id)
+	if err != nil {
+		return err
+	}
+	sm, ok := s.notifications.(SubscriptionManager)
+	if !ok {
+		return errSubscriptionsUnsupported
+	}
+	tt, tid := subscriptionThread(id)
+	return sm.Unsubscribe(ctx, notifications.RepoSpec(repo), tt, tid, []users.UserSpec{currentUser})
+}
+
+// ListSubscribers implements issues.Subscriber.
+func (s *service) ListSubscribers(ctx context.Context, repo issues.RepoSpec, id uint64) ([]users.User, error) {
+	if id != 0 {
+		// Make sure the issue exists.
+		s.fsMu.RLock()
+		_, err := s.fs.Stat(ctx, issueCommentPath(repo, id, 0))
+		s.fsMu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sm, ok := s.notifications.(SubscriptionManager)
+	if !ok {
+		return nil, errSubscriptionsUnsupported
+	}
+	tt, tid := subscriptionThread(id)
+	subscribers, err := sm.ListSubscribers(ctx, notifications.RepoSpec(repo), tt, tid)
+	if err != nil {
+		return nil, err
+	}
+	var us []users.User
+	for _, u := range subscribers {
+		us = append(us, s.user(ctx, u))
+	}
+	return us, nil
+}
+
+// subscriptionUser returns the authenticated user, who is about to change their
+// subscription to the specified issue, or to repo if id is 0.
+func (s *service) subscriptionUser(ctx context.Context, repo issues.RepoSpec, id uint64) (users.UserSpec, error) {
+	currentUser, err := s.users.GetAuthenticatedSpec(ctx)
+	if err != nil {
+		return users.UserSpec{}, err
+	}
+	if currentUser.ID == 0 {
+		return users.UserSpec{}, os.ErrPermission
+	}
+	if id != 0 {
+		// Make sure the issue exists.
+		s.fsMu.RLock()
+		_, err := s.fs.Stat(ctx, issueCommentPath(repo, id, 0))
+		s.fsMu.RUnlock()
+		if err != nil {
+			return users.UserSpec{}, err
+		}
+	}
+	return currentUser, nil
+}
@@ -0,0 +1,63 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/webdavfs/vfsutil"
+)
+
+// ListTemplates implements issues.TemplateLister.
+func (s *service) ListTemplates(ctx context.Context, repo issues.RepoSpec) ([]issues.Template, error) {
+	s.fsMu.RLock()
+	defer s.fsMu.RUnlock()
+
+	fis, err := vfsutil.ReadDir(ctx, s.fs, templatesDir(repo))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	var ts []issues.Template
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		var t template
+		err := jsonDecodeFile(ctx, s.fs, templatePath(repo, fi.Name()), &t)
+		if err != nil {
+			return ts, err
+		}
+		ts = append(ts, t.Template(fi.Name()))
+	}
+	return ts, nil
+}
+
+// CreateFromTemplate implements issues.TemplateLister.
+func (s *service) CreateFromTemplate(ctx context.Context, repo issues.RepoSpec, name string, i issues.Issue) (issues.Issue, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, &issues.InvalidArgumentError{Field: "template", Reason: fmt.Sprintf("invalid template name %q", name)}
+	}
+	s.fsMu.RLock()
+	var t template
+	err := jsonDecodeFile(ctx, s.fs, templatePath(repo, name), &t)
+	s.fsMu.RUnlock()
+	if os.IsNotExist(err) {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, &issues.InvalidArgumentError{Field: "template", Reason: fmt.Sprintf("template %q doesn't exist", name)}
+	} else if err != nil {
+		return issues.Issue{}, err
+	}
+	tmpl := t.Template(name)
+	i = tmpl.Apply(i)
+	if err := tmpl.ValidateIssue(i); err != nil {
+		return issues.Issue{}, err
+	}
+	return s.Create(ctx, repo, i)
+}
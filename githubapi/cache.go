@@ -0,0 +1,299 @@
+package githubapi
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is a cache of GitHub API responses. It's used via an http.RoundTripper
+// returned by its Transport method, in the HTTP clients of a service.
+//
+// GraphQL API v4 query results are kept for a TTL, keyed by query, variables and
+// the viewer (the Authorization header of the request). REST API v3 GET responses
+// are kept until evicted, and revalidated with their ETag on every request;
+// GitHub doesn't count unmodified responses against the rate limit.
+// Mutations, and REST API v3 requests other than GET, invalidate all cached
+// GraphQL query results, since they may have changed any of them. They do so
+// both when sent and when their response arrives, and results of queries that
+// were in flight meanwhile aren't cached, since they may predate the mutation.
+//
+// Entries are evicted in least recently used order when the cache is full.
+type Cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // Key -> element with *cacheEntry value.
+	lru     *list.List               // Most recently used first.
+	gen     uint64                   // Gen is incremented by each invalidation of GraphQL query results.
+	stats   CacheStats
+}
+
+// NewCache creates a cache with room for size responses,
+// which keeps GraphQL query results for ttl.
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// CacheStats are statistics of a Cache.
+type CacheStats struct {
+	Hits        uint64 // Hits is the number of GraphQL query results served from cache.
+	Revalidated uint64 // Revalidated is the number of REST responses served from cache after an ETag check.
+	Misses      uint64 // Misses is the number of cacheable requests that needed a full response.
+	Evictions   uint64 // Evictions is the number of entries evicted to make room for others.
+}
+
+// HitRate returns the fraction of cacheable requests served from cache.
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Revalidated + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits+s.Revalidated) / float64(total)
+}
+
+// Stats returns statistics of c.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Transport returns an http.RoundTripper that caches responses in c,
+// and makes requests via base, or http.DefaultTransport if base is nil.
+// It must come after authentication, so that it can tell viewers apart.
+// For example, use it as the Base of an oauth2.Transport, or as
+// the transport of NewMultiUserService.
+func (c *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return cacheTransport{c: c, base: base}
+}
+
+type cacheEntry struct {
+	key     string
+	graphQL bool
+	expires time.Time // Expires is only set for GraphQL query results.
+	gen     uint64    // Gen is the cache generation when a GraphQL query was sent.
+	etag    string    // ETag is only set for REST responses.
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+type cacheTransport struct {
+	c    *Cache
+	base http.RoundTripper
+}
+
+func (t cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.Method == http.MethodGet:
+		return t.roundTripREST(req)
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/graphql"):
+		return t.roundTripGraphQL(req)
+	default:
+		// A REST API v3 mutation.
+		return t.roundTripMutation(req)
+	}
+}
+
+// roundTripMutation makes the mutation req, invalidating GraphQL query results
+// before it's sent and after its response arrives.
+func (t cacheTransport) roundTripMutation(req *http.Request) (*http.Response, error) {
+	t.c.invalidateGraphQL()
+	defer t.c.invalidateGraphQL()
+	return t.base.RoundTrip(req)
+}
+
+func (t cacheTransport) roundTripREST(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req, nil)
+	e, ok := t.c.get(key)
+	if ok && e.etag != "" {
+		req = req.Clone(req.Context()) // Per RoundTripper contract, don't modify req.
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ok && resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		t.c.count(func(s *CacheStats) { s.Revalidated++ })
+		return e.response(req), nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		t.c.count(func(s *CacheStats) { s.Misses++ })
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		t.c.put(&cacheEntry{key: key, etag: resp.Header.Get("ETag"), status: resp.StatusCode, header: resp.Header.Clone(), body: body})
+		return resp, nil
+	default:
+		return resp, nil
+	}
+}
+
+func (t cacheTransport) roundTripGraphQL(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	req = req.Clone(req.Context()) // Per RoundTripper contract, don't modify req.
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if isGraphQLMutation(body) {
+		return t.roundTripMutation(req)
+	}
+
+	key := cacheKey(req, body)
+	e, gen, ok := t.c.getGraphQL(key)
+	if ok {
+		t.c.count(func(s *CacheStats) { s.Hits++ })
+		return e.response(req), nil
+	}
+	t.c.count(func(s *CacheStats) { s.Misses++ })
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	var r struct{ Errors json.RawMessage }
+	if json.Unmarshal(respBody, &r) != nil || r.Errors != nil {
+		// Don't cache errors, they may be temporary.
+		return resp, nil
+	}
+	t.c.put(&cacheEntry{key: key, graphQL: true, expires: time.Now().Add(t.c.ttl), gen: gen, status: resp.StatusCode, header: resp.Header.Clone(), body: respBody})
+	return resp, nil
+}
+
+// isGraphQLMutation reports whether the GraphQL request body is a mutation.
+func isGraphQLMutation(body []byte) bool {
+	var q struct{ Query string }
+	json.Unmarshal(body, &q)
+	return strings.HasPrefix(strings.TrimSpace(q.Query), "mutation")
+}
+
+// cacheKey returns the cache key for req with body.
+func cacheKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Header.Get("Authorization"))
+	h.Write([]byte{0})
+	io.WriteString(h, req.Header.Get("Accept"))
+	h.Write([]byte{0})
+	h.Write(body)
+	return req.Method + " " + req.URL.String() + " " + hex.EncodeToString(h.Sum(nil))
+}
+
+// response returns a response to req from e.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// get returns the unexpired entry with key, if any.
+func (c *Cache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if e.graphQL && time.Now().After(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// getGraphQL is like get, and also returns the current cache generation.
+func (c *Cache) getGraphQL(key string) (*cacheEntry, uint64, bool) {
+	e, ok := c.get(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return e, c.gen, ok
+}
+
+// put adds entry e, evicting the least recently used entries if c is full.
+// GraphQL query results aren't added if GraphQL query results were
+// invalidated since the query was sent.
+func (c *Cache) put(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.graphQL && e.gen != c.gen {
+		return
+	}
+	if el, ok := c.entries[e.key]; ok {
+		c.lru.Remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+// invalidateGraphQL removes all GraphQL query results.
+func (c *Cache) invalidateGraphQL() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*cacheEntry); e.graphQL {
+			c.lru.Remove(el)
+			delete(c.entries, e.key)
+		}
+		el = next
+	}
+}
+
+func (c *Cache) count(f func(*CacheStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(&c.stats)
+}
@@ -0,0 +1,206 @@
+package githubapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheGraphQL(t *testing.T) {
+	var requests int
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"viewer":{"login":"gopher"}}}`)),
+			Request:    req,
+		}, nil
+	})
+	c := NewCache(10, time.Minute)
+	cl := &http.Client{Transport: c.Transport(upstream)}
+	post := func(token, query string) string {
+		req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"`+query+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "bearer "+token)
+		resp, err := cl.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	for _, want := range []struct {
+		token, query string
+		requests     int
+	}{
+		{"alice", "{viewer{login}}", 1},
+		{"alice", "{viewer{login}}", 1}, // Hit.
+		{"bob", "{viewer{login}}", 2},   // Different viewer.
+		{"alice", "mutation{x}", 3},     // Mutations invalidate query results.
+		{"alice", "{viewer{login}}", 4},
+		{"alice", "{viewer{login}}", 4}, // Hit.
+	} {
+		if got := post(want.token, want.query); got != `{"data":{"viewer":{"login":"gopher"}}}` {
+			t.Errorf("got body %q", got)
+		}
+		if requests != want.requests {
+			t.Errorf("after %s %q: got %d upstream requests, want %d", want.token, want.query, requests, want.requests)
+		}
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 2, Misses: 3}); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+	if got, want := c.Stats().HitRate(), 0.4; got != want {
+		t.Errorf("got hit rate %v, want %v", got, want)
+	}
+
+	// Expired results aren't served.
+	c = NewCache(10, time.Nanosecond)
+	cl = &http.Client{Transport: c.Transport(upstream)}
+	requests = 0
+	post("alice", "{viewer{login}}")
+	time.Sleep(time.Millisecond)
+	post("alice", "{viewer{login}}")
+	if requests != 2 {
+		t.Errorf("got %d upstream requests with expired results, want 2", requests)
+	}
+
+	// Least recently used results are evicted.
+	c = NewCache(1, time.Minute)
+	cl = &http.Client{Transport: c.Transport(upstream)}
+	post("alice", "{a}")
+	post("alice", "{b}")
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("got %d evictions, want 1", got)
+	}
+}
+
+func TestCacheETag(t *testing.T) {
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": {`"v1"`}},
+			Body:       io.NopCloser(strings.NewReader(`{"number":1}`)),
+			Request:    req,
+		}, nil
+	})
+	c := NewCache(10, time.Minute)
+	cl := &http.Client{Transport: c.Transport(upstream)}
+	for i := 0; i < 2; i++ {
+		resp, err := cl.Get("https://api.github.com/repos/owner/repo/issues/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(body) != `{"number":1}` {
+			t.Errorf("request %d: got %d %q, want 200 with the issue", i, resp.StatusCode, body)
+		}
+	}
+	if got, want := c.Stats(), (CacheStats{Revalidated: 1, Misses: 1}); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheGraphQLConcurrentMutation(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		version = 1 // Version of the data, incremented by mutations.
+	)
+	queryBlock := make(chan chan struct{}, 1)    // Receives a channel the next query waits on after reading data.
+	mutationBlock := make(chan chan struct{}, 1) // Receives a channel the next mutation waits on before changing data.
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		if isGraphQLMutation(body) {
+			select {
+			case wait := <-mutationBlock:
+				<-wait
+			default:
+			}
+			mu.Lock()
+			version++
+			mu.Unlock()
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"data":{}}`)), Request: req}, nil
+		}
+		mu.Lock()
+		v := version
+		mu.Unlock()
+		select {
+		case wait := <-queryBlock:
+			<-wait
+		default:
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"data":{"version":%d}}`, v))),
+			Request:    req,
+		}, nil
+	})
+	c := NewCache(10, time.Minute)
+	cl := &http.Client{Transport: c.Transport(upstream)}
+	post := func(query string) string {
+		resp, err := cl.Post("https://api.github.com/graphql", "application/json", strings.NewReader(`{"query":"`+query+`"}`))
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		return string(body)
+	}
+
+	// A query reads data, then a mutation completes before the query's response arrives.
+	wait := make(chan struct{})
+	queryBlock <- wait
+	done := make(chan string)
+	go func() { done <- post("{version}") }()
+	for len(queryBlock) != 0 {
+		time.Sleep(time.Millisecond) // Wait for the query to read data.
+	}
+	post("mutation{x}")
+	close(wait)
+	if got, want := <-done, `{"data":{"version":1}}`; got != want {
+		t.Errorf("got %q from the query in flight, want %q", got, want)
+	}
+	if got, want := post("{version}"), `{"data":{"version":2}}`; got != want {
+		t.Errorf("got %q after the mutation, want %q; a stale result was cached", got, want)
+	}
+
+	// A query is made and answered while a mutation is in flight.
+	wait = make(chan struct{})
+	mutationBlock <- wait
+	go func() { done <- post("mutation{x}") }()
+	for len(mutationBlock) != 0 {
+		time.Sleep(time.Millisecond) // Wait for the mutation to be sent.
+	}
+	if got, want := post("{version}"), `{"data":{"version":2}}`; got != want {
+		t.Errorf("got %q during the mutation, want %q", got, want)
+	}
+	close(wait)
+	<-done
+	if got, want := post("{version}"), `{"data":{"version":3}}`; got != want {
+		t.Errorf("got %q after the mutation, want %q; a stale result was cached", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package githubapi
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// ListFields implements issues.FieldLister.
+// Custom fields are fields of GitHub Projects (v2) that the repo is linked to.
+// They're read-only via this API. Number fields are IntFields,
+// and their non-integral values are rounded to the nearest integer.
+func (s service) ListFields(ctx context.Context, rs issues.RepoSpec) ([]issues.FieldDef, error) {
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, err
+	}
+	var q struct {
+		Repository struct {
+			ProjectsV2 struct {
+				Nodes []struct {
+					Fields struct {
+						Nodes []struct {
+							Common struct {
+								Name     string
+								DataType string
+							} `graphql:"...on ProjectV2FieldCommon"`
+							SingleSelect struct {
+								Options []struct{ Name string }
+							} `graphql:"...on ProjectV2SingleSelectField"`
+						}
+					} `graphql:"fields(first:50)"`
+				}
+			} `graphql:"projectsV2(first:20)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+	}
+	err = s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		defs []issues.FieldDef
+		seen = make(map[string]bool)
+	)
+	for _, p := range q.Repository.ProjectsV2.Nodes {
+		for _, f := range p.Fields.Nodes {
+			d := issues.FieldDef{Name: f.Common.Name, ReadOnly: true}
+			switch f.Common.DataType {
+			case "SINGLE_SELECT":
+				d.Type = issues.EnumField
+				for _, o := range f.SingleSelect.Options {
+					d.Options = append(d.Options, o.Name)
+				}
+			case "NUMBER":
+				d.Type = issues.IntField
+			case "TEXT":
+				d.Type = issues.StringField
+			case "DATE":
+				d.Type = issues.DateField
+			case "ASSIGNEES":
+				d.Type = issues.UserField
+			default:
+				// Fields like title, labels and iterations aren't custom fields, or aren't supported.
+				continue
+			}
+			if seen[d.Name] {
+				// The first project with a field of a given name wins.
+				continue
+			}
+			seen[d.Name] = true
+			defs = append(defs, d)
+		}
+	}
+	return defs, nil
+}
+
+// projectFields fetches values of custom fields of the specified issue
+// from GitHub Projects (v2) it's in. See ListFields.
+func (s service) projectFields(ctx context.Context, repo repoSpec, id uint64) ([]issues.Field, error) {
+	type field struct {
+		Common struct {
+			Name string
+		} `graphql:"...on ProjectV2FieldCommon"`
+	}
+	var q struct {
+		Repository struct {
+			Issue struct {
+				ProjectItems struct {
+					Nodes []struct {
+						FieldValues struct {
+							Nodes []struct {
+								Typename     string `graphql:"__typename"`
+								SingleSelect struct {
+									Name  string
+									Field field
+								} `graphql:"...on ProjectV2ItemFieldSingleSelectValue"`
+								Number struct {
+									Number float64
+									Field  field
+								} `graphql:"...on ProjectV2ItemFieldNumberValue"`
+								Text struct {
+									Text  string
+									Field field
+								} `graphql:"...on ProjectV2ItemFieldTextValue"`
+								Date struct {
+									Date  string
+									Field field
+								} `graphql:"...on ProjectV2ItemFieldDateValue"`
+								User struct {
+									Users struct {
+										Nodes []struct{ DatabaseID uint64 }
+									} `graphql:"users(first:1)"`
+									Field field
+								} `graphql:"...on ProjectV2ItemFieldUserValue"`
+							}
+						} `graphql:"fieldValues(first:50)"`
+					}
+				} `graphql:"projectItems(first:10)"`
+			} `graphql:"issue(number:$issueNumber)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"issueNumber":     githubv4.Int(id),
+	}
+	err := s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		fields []issues.Field
+		seen   = make(map[string]bool)
+	)
+	for _, item := range q.Repository.Issue.ProjectItems.Nodes {
+		for _, v := range item.FieldValues.Nodes {
+			var f issues.Field
+			switch v.Typename {
+			case "ProjectV2ItemFieldSingleSelectValue":
+				f = issues.Field{Name: v.SingleSelect.Field.Common.Name, Value: v.SingleSelect.Name}
+			case "ProjectV2ItemFieldNumberValue":
+				// Number fields are exposed as IntFields, so non-integral values
+				// are rounded to the nearest integer.
+				f = issues.Field{Name: v.Number.Field.Common.Name, Value: int64(math.Round(v.Number.Number))}
+			case "ProjectV2ItemFieldTextValue":
+				if v.Text.Field.Common.Name == "Title" {
+					// The title of the item isn't a custom field.
+					continue
+				}
+				f = issues.Field{Name: v.Text.Field.Common.Name, Value: v.Text.Text}
+			case "ProjectV2ItemFieldDateValue":
+				t, err := time.Parse("2006-01-02", v.Date.Date)
+				if err != nil {
+					continue
+				}
+				f = issues.Field{Name: v.Date.Field.Common.Name, Value: t}
+			case "ProjectV2ItemFieldUserValue":
+				if len(v.User.Users.Nodes) == 0 {
+					continue
+				}
+				f = issues.Field{Name: v.User.Field.Common.Name, Value: users.UserSpec{ID: v.User.Users.Nodes[0].DatabaseID, Domain: "github.com"}}
+			default:
+				continue
+			}
+			if seen[f.Name] {
+				continue
+			}
+			seen[f.Name] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
@@ -6,41 +6,45 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
 	"dmitri.shuralyov.com/route/github"
 	"dmitri.shuralyov.com/state"
-	githubv3 "github.com/google/go-github/github"
 	"github.com/shurcooL/githubv4"
 	"github.com/shurcooL/issues"
 	"github.com/shurcooL/notifications"
+	"github.com/shurcooL/reactions"
 	"github.com/shurcooL/users"
 )
 
-// NewService creates a GitHub-backed issues.Service using given GitHub clients.
-// It uses notifications service, if not nil. At this time it infers the current user
-// from GitHub clients (their authentication info), and cannot be used to serve multiple users.
-// Both GitHub clients must use same authentication info.
+// NewService creates a GitHub-backed issues.Service using given GitHub GraphQL API v4 client.
+// It uses notifications service, if not nil. It infers the current user
+// from the GitHub client (its authentication info), and cannot be used to serve multiple users.
+// Use NewMultiUserService to serve multiple users. Use Cache to cache responses,
+// and RetryTransport to handle rate limits and server errors.
 //
 // If router is nil, github.DotCom router is used, which links to subjects on github.com.
-func NewService(clientV3 *githubv3.Client, clientV4 *githubv4.Client, notifications notifications.ExternalService, router github.Router) issues.Service {
+func NewService(client *githubv4.Client, notifications notifications.ExternalService, router github.Router) issues.Service {
 	if router == nil {
 		router = github.DotCom{}
 	}
 	return service{
-		clV3:          clientV3,
-		clV4:          clientV4,
+		cl:            client,
 		rtr:           router,
 		notifications: notifications,
 	}
 }
 
 type service struct {
-	clV3 *githubv3.Client // GitHub REST API v3 client.
-	clV4 *githubv4.Client // GitHub GraphQL API v4 client.
-	rtr  github.Router
+	cl  *githubv4.Client // GitHub GraphQL API v4 client.
+	rtr github.Router
+
+	// users, if not nil, provides per-user clients, which are used instead of cl.
+	users *userClients
 
 	// notifications may be nil if there's no notifications service.
 	notifications notifications.ExternalService
@@ -55,6 +59,14 @@ func (s service) List(ctx context.Context, rs issues.RepoSpec, opt issues.IssueL
 		// TODO: Map to 400 Bad Request HTTP error.
 		return nil, err
 	}
+	if len(opt.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, &issues.InvalidArgumentError{Field: "Fields", Reason: "filtering by custom fields is not supported"}
+	}
+	if opt.Sort != "" {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, &issues.InvalidArgumentError{Field: "Sort", Reason: "sorting by custom fields is not supported"}
+	}
 	var states *[]githubv4.IssueState
 	switch opt.State {
 	case issues.StateFilter(issues.OpenState):
@@ -94,7 +106,7 @@ func (s service) List(ctx context.Context, rs issues.RepoSpec, opt issues.IssueL
 		"repositoryName":  githubv4.String(repo.Repo),
 		"issuesStates":    states,
 	}
-	err = s.clV4.Query(ctx, &q, variables)
+	err = s.v4(ctx).Query(ctx, &q, variables)
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +140,14 @@ func (s service) Count(ctx context.Context, rs issues.RepoSpec, opt issues.Issue
 		// TODO: Map to 400 Bad Request HTTP error.
 		return 0, err
 	}
+	if len(opt.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return 0, &issues.InvalidArgumentError{Field: "Fields", Reason: "filtering by custom fields is not supported"}
+	}
+	if opt.Sort != "" {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return 0, &issues.InvalidArgumentError{Field: "Sort", Reason: "sorting by custom fields is not supported"}
+	}
 	var states *[]githubv4.IssueState
 	switch opt.State {
 	case issues.StateFilter(issues.OpenState):
@@ -152,7 +172,7 @@ func (s service) Count(ctx context.Context, rs issues.RepoSpec, opt issues.Issue
 		"repositoryName":  githubv4.String(repo.Repo),
 		"issuesStates":    states,
 	}
-	err = s.clV4.Query(ctx, &q, variables)
+	err = s.v4(ctx).Query(ctx, &q, variables)
 	return q.Repository.Issues.TotalCount, err
 }
 
@@ -164,22 +184,16 @@ func (s service) Get(ctx context.Context, rs issues.RepoSpec, id uint64) (issues
 	}
 	var q struct {
 		Repository struct {
-			Issue struct {
-				Number          uint64
-				State           githubv4.IssueState
-				Title           string
-				Author          *githubV4Actor
-				CreatedAt       githubv4.DateTime
-				ViewerCanUpdate githubv4.Boolean
-			} `graphql:"issue(number:$issueNumber)"`
+			Issue githubV4Issue `graphql:"issue(number:$issueNumber)"`
 		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+		Viewer githubV4User
 	}
 	variables := map[string]interface{}{
 		"repositoryOwner": githubv4.String(repo.Owner),
 		"repositoryName":  githubv4.String(repo.Repo),
 		"issueNumber":     githubv4.Int(id),
 	}
-	err = s.clV4.Query(ctx, &q, variables)
+	err = s.v4(ctx).Query(ctx, &q, variables)
 	if err != nil {
 		return issues.Issue{}, err
 	}
@@ -190,18 +204,16 @@ func (s service) Get(ctx context.Context, rs issues.RepoSpec, id uint64) (issues
 		log.Println("service.Get: failed to markRead:", err)
 	}
 
-	// TODO: Eliminate comment body properties from issues.Issue. It's missing increasingly more fields, like Edited, etc.
-	issue := q.Repository.Issue
-	return issues.Issue{
-		ID:    issue.Number,
-		State: ghIssueState(issue.State),
-		Title: issue.Title,
-		Comment: issues.Comment{
-			User:      ghActor(issue.Author),
-			CreatedAt: issue.CreatedAt.Time,
-			Editable:  bool(issue.ViewerCanUpdate),
-		},
-	}, nil
+	// Custom fields come from GitHub Projects, which may need additional token scopes,
+	// so failing to fetch them doesn't fail Get.
+	fields, err := s.projectFields(ctx, repo, id)
+	if err != nil {
+		log.Println("service.Get: failed to projectFields:", err)
+	}
+
+	issue := ghIssue(q.Repository.Issue, ghUser(&q.Viewer))
+	issue.Fields = fields
+	return issue, nil
 }
 
 // ListComments used to list only comments, but isn't implemented anymore.
@@ -226,52 +238,25 @@ func (s service) ListTimeline(ctx context.Context, rs issues.RepoSpec, id uint64
 		// TODO: Map to 400 Bad Request HTTP error.
 		return nil, err
 	}
-	type comment struct { // Comment fields.
-		Author          *githubV4Actor
-		PublishedAt     githubv4.DateTime
-		LastEditedAt    *githubv4.DateTime
-		Editor          *githubV4Actor
-		Body            string
-		ReactionGroups  reactionGroups
-		ViewerCanUpdate bool
-	}
 	type event struct { // Common fields for all events.
+		ID        string
 		Actor     *githubV4Actor
 		CreatedAt githubv4.DateTime
 	}
 	var q struct {
 		Repository struct {
 			Issue struct {
-				comment  `graphql:"...@include(if:$firstPage)"` // Fetch the issue description only on first page.
-				Timeline struct {
+				githubV4Comment `graphql:"...@include(if:$firstPage)"` // Fetch the issue description only on first page.
+				Timeline        struct {
 					Nodes []struct {
 						Typename     string `graphql:"__typename"`
 						IssueComment struct {
 							DatabaseID uint64
-							comment
+							githubV4Comment
 						} `graphql:"...on IssueComment"`
 						ClosedEvent struct {
 							event
-							Closer struct {
-								Typename    string `graphql:"__typename"`
-								PullRequest struct {
-									State      githubv4.PullRequestState
-									Title      string
-									Repository struct {
-										Owner struct{ Login string }
-										Name  string
-									}
-									Number uint64
-								} `graphql:"...on PullRequest"`
-								Commit struct {
-									OID     string
-									Message string
-									Author  struct {
-										AvatarURL string `graphql:"avatarUrl(size:96)"`
-									}
-									URL string
-								} `graphql:"...on Commit"`
-							}
+							Closer *githubV4Closer
 						} `graphql:"...on ClosedEvent"`
 						ReopenedEvent struct {
 							event
@@ -295,6 +280,52 @@ func (s service) ListTimeline(ctx context.Context, rs issues.RepoSpec, id uint64
 								Color string
 							}
 						} `graphql:"...on UnlabeledEvent"`
+						MilestonedEvent struct {
+							event
+							MilestoneTitle string
+						} `graphql:"...on MilestonedEvent"`
+						DemilestonedEvent struct {
+							event
+							MilestoneTitle string
+						} `graphql:"...on DemilestonedEvent"`
+						CommentDeletedEvent struct {
+							event
+						} `graphql:"...on CommentDeletedEvent"`
+						CrossReferencedEvent struct {
+							event
+							Source struct {
+								Typename string `graphql:"__typename"`
+								Issue    struct {
+									Repository struct {
+										Owner struct{ Login string }
+										Name  string
+									}
+									Number uint64
+									Title  string
+								} `graphql:"...on Issue"`
+								PullRequest struct {
+									State      githubv4.PullRequestState
+									Title      string
+									Repository struct {
+										Owner struct{ Login string }
+										Name  string
+									}
+									Number uint64
+								} `graphql:"...on PullRequest"`
+							}
+							URL string
+						} `graphql:"...on CrossReferencedEvent"`
+						ReferencedEvent struct {
+							event
+							Commit *struct {
+								OID     string
+								Message string
+								Author  struct {
+									AvatarURL string `graphql:"avatarUrl(size:96)"`
+								}
+								URL string
+							}
+						} `graphql:"...on ReferencedEvent"`
 					}
 					PageInfo struct {
 						EndCursor   githubv4.String
@@ -314,89 +345,40 @@ func (s service) ListTimeline(ctx context.Context, rs issues.RepoSpec, id uint64
 	}
 	var timeline []interface{} // Of type issues.Comment and issues.Event.
 	for {
-		err := s.clV4.Query(ctx, &q, variables)
+		err := s.v4(ctx).Query(ctx, &q, variables)
 		if err != nil {
 			return timeline, err
 		}
+		viewer := ghUser(&q.Viewer)
 		if variables["firstPage"].(githubv4.Boolean) {
-			issue := q.Repository.Issue.comment // Issue description comment.
-			var edited *issues.Edited
-			if issue.LastEditedAt != nil {
-				edited = &issues.Edited{
-					By: ghActor(issue.Editor),
-					At: issue.LastEditedAt.Time,
-				}
-			}
-			timeline = append(timeline, issues.Comment{
-				ID:        issueDescriptionCommentID,
-				User:      ghActor(issue.Author),
-				CreatedAt: issue.PublishedAt.Time,
-				Edited:    edited,
-				Body:      issue.Body,
-				Reactions: ghReactions(issue.ReactionGroups, ghUser(&q.Viewer)),
-				Editable:  issue.ViewerCanUpdate,
-			})
+			// Issue description comment.
+			timeline = append(timeline, ghComment(issueDescriptionCommentID, q.Repository.Issue.githubV4Comment, viewer))
 		}
 		for _, n := range q.Repository.Issue.Timeline.Nodes {
 			switch n.Typename {
 			case "IssueComment":
 				comment := n.IssueComment
-				var edited *issues.Edited
-				if comment.LastEditedAt != nil {
-					edited = &issues.Edited{
-						By: ghActor(comment.Editor),
-						At: comment.LastEditedAt.Time,
-					}
-				}
-				timeline = append(timeline, issues.Comment{
-					ID:        comment.DatabaseID,
-					User:      ghActor(comment.Author),
-					CreatedAt: comment.PublishedAt.Time,
-					Edited:    edited,
-					Body:      comment.Body,
-					Reactions: ghReactions(comment.ReactionGroups, ghUser(&q.Viewer)),
-					Editable:  comment.ViewerCanUpdate,
-				})
+				timeline = append(timeline, ghComment(comment.DatabaseID, comment.githubV4Comment, viewer))
 			default:
 				et := ghEventType(n.Typename)
 				if !et.Valid() {
 					continue
 				}
 				e := issues.Event{
-					//ID:   0, // TODO.
 					Type: et,
 				}
 				switch et {
 				case issues.Closed:
+					e.ID = ghEventID(n.ClosedEvent.ID)
 					e.Actor = ghActor(n.ClosedEvent.Actor)
 					e.CreatedAt = n.ClosedEvent.CreatedAt.Time
-					switch n.ClosedEvent.Closer.Typename {
-					case "PullRequest":
-						pr := n.ClosedEvent.Closer.PullRequest
-						e.Close = issues.Close{
-							Closer: issues.Change{
-								State:   ghPRState(pr.State),
-								Title:   pr.Title,
-								HTMLURL: s.rtr.PullRequestURL(ctx, pr.Repository.Owner.Login, pr.Repository.Name, pr.Number),
-							},
-						}
-					case "Commit":
-						c := n.ClosedEvent.Closer.Commit
-						e.Close = issues.Close{
-							Closer: issues.Commit{
-								SHA:             c.OID,
-								Message:         c.Message,
-								AuthorAvatarURL: c.Author.AvatarURL,
-								HTMLURL:         c.URL,
-							},
-						}
-					default:
-						e.Close = issues.Close{}
-					}
+					e.Close = s.ghClose(ctx, n.ClosedEvent.Closer)
 				case issues.Reopened:
+					e.ID = ghEventID(n.ReopenedEvent.ID)
 					e.Actor = ghActor(n.ReopenedEvent.Actor)
 					e.CreatedAt = n.ReopenedEvent.CreatedAt.Time
 				case issues.Renamed:
+					e.ID = ghEventID(n.RenamedTitleEvent.ID)
 					e.Actor = ghActor(n.RenamedTitleEvent.Actor)
 					e.CreatedAt = n.RenamedTitleEvent.CreatedAt.Time
 					e.Rename = &issues.Rename{
@@ -404,6 +386,7 @@ func (s service) ListTimeline(ctx context.Context, rs issues.RepoSpec, id uint64
 						To:   n.RenamedTitleEvent.CurrentTitle,
 					}
 				case issues.Labeled:
+					e.ID = ghEventID(n.LabeledEvent.ID)
 					e.Actor = ghActor(n.LabeledEvent.Actor)
 					e.CreatedAt = n.LabeledEvent.CreatedAt.Time
 					e.Label = &issues.Label{
@@ -411,12 +394,77 @@ func (s service) ListTimeline(ctx context.Context, rs issues.RepoSpec, id uint64
 						Color: ghColor(n.LabeledEvent.Label.Color),
 					}
 				case issues.Unlabeled:
+					e.ID = ghEventID(n.UnlabeledEvent.ID)
 					e.Actor = ghActor(n.UnlabeledEvent.Actor)
 					e.CreatedAt = n.UnlabeledEvent.CreatedAt.Time
 					e.Label = &issues.Label{
 						Name:  n.UnlabeledEvent.Label.Name,
 						Color: ghColor(n.UnlabeledEvent.Label.Color),
 					}
+				case issues.Milestoned:
+					e.ID = ghEventID(n.MilestonedEvent.ID)
+					e.Actor = ghActor(n.MilestonedEvent.Actor)
+					e.CreatedAt = n.MilestonedEvent.CreatedAt.Time
+					e.Milestone = &issues.Milestone{
+						Name: n.MilestonedEvent.MilestoneTitle,
+					}
+				case issues.Demilestoned:
+					e.ID = ghEventID(n.DemilestonedEvent.ID)
+					e.Actor = ghActor(n.DemilestonedEvent.Actor)
+					e.CreatedAt = n.DemilestonedEvent.CreatedAt.Time
+					e.Milestone = &issues.Milestone{
+						Name: n.DemilestonedEvent.MilestoneTitle,
+					}
+				case issues.CommentDeleted:
+					e.ID = ghEventID(n.CommentDeletedEvent.ID)
+					e.Actor = ghActor(n.CommentDeletedEvent.Actor)
+					e.CreatedAt = n.CommentDeletedEvent.CreatedAt.Time
+				case issues.CrossReferenced:
+					switch n.Typename {
+					case "CrossReferencedEvent":
+						e.ID = ghEventID(n.CrossReferencedEvent.ID)
+						e.Actor = ghActor(n.CrossReferencedEvent.Actor)
+						e.CreatedAt = n.CrossReferencedEvent.CreatedAt.Time
+						switch src := n.CrossReferencedEvent.Source; src.Typename {
+						case "Issue":
+							e.CrossReference = &issues.CrossReference{
+								Source: issues.IssueRef{
+									Repo:    issues.RepoSpec{URI: "github.com/" + src.Issue.Repository.Owner.Login + "/" + src.Issue.Repository.Name},
+									ID:      src.Issue.Number,
+									Title:   src.Issue.Title,
+									HTMLURL: n.CrossReferencedEvent.URL,
+								},
+							}
+						case "PullRequest":
+							pr := src.PullRequest
+							e.CrossReference = &issues.CrossReference{
+								Source: issues.Change{
+									State:   ghPRState(pr.State),
+									Title:   pr.Title,
+									HTMLURL: s.rtr.PullRequestURL(ctx, pr.Repository.Owner.Login, pr.Repository.Name, pr.Number),
+								},
+							}
+						default:
+							continue
+						}
+					case "ReferencedEvent":
+						c := n.ReferencedEvent.Commit
+						if c == nil {
+							// The commit may no longer be accessible.
+							continue
+						}
+						e.ID = ghEventID(n.ReferencedEvent.ID)
+						e.Actor = ghActor(n.ReferencedEvent.Actor)
+						e.CreatedAt = n.ReferencedEvent.CreatedAt.Time
+						e.CrossReference = &issues.CrossReference{
+							Source: issues.Commit{
+								SHA:             c.OID,
+								Message:         c.Message,
+								AuthorAvatarURL: c.Author.AvatarURL,
+								HTMLURL:         c.URL,
+							},
+						}
+					}
 				default:
 					continue
 				}
@@ -463,7 +511,7 @@ func (s service) CreateComment(ctx context.Context, rs issues.RepoSpec, id uint6
 		"repositoryName":  githubv4.String(repo.Repo),
 		"issueNumber":     githubv4.Int(id),
 	}
-	err = s.clV4.Query(ctx, &q, variables)
+	err = s.v4(ctx).Query(ctx, &q, variables)
 	if err != nil {
 		return issues.Comment{}, err
 	}
@@ -484,7 +532,7 @@ func (s service) CreateComment(ctx context.Context, rs issues.RepoSpec, id uint6
 		SubjectID: q.Repository.Issue.ID,
 		Body:      githubv4.String(c.Body),
 	}
-	err = s.clV4.Mutate(ctx, &m, input, nil)
+	err = s.v4(ctx).Mutate(ctx, &m, input, nil)
 	if err != nil {
 		return issues.Comment{}, err
 	}
@@ -503,25 +551,45 @@ func (s service) Create(ctx context.Context, rs issues.RepoSpec, i issues.Issue)
 	if err != nil {
 		return issues.Issue{}, err
 	}
-	issue, _, err := s.clV3.Issues.Create(ctx, repo.Owner, repo.Repo, &githubv3.IssueRequest{
-		Title: &i.Title,
-		Body:  &i.Body,
-	})
+	if len(i.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, &issues.InvalidArgumentError{Field: "Fields", Reason: "custom fields are read-only"}
+	}
+	var labels *[]issues.Label
+	if len(i.Labels) > 0 {
+		labels = &i.Labels
+	}
+	var assignees *[]users.UserSpec
+	if len(i.Assignees) > 0 {
+		var us []users.UserSpec
+		for _, u := range i.Assignees {
+			us = append(us, u.UserSpec)
+		}
+		assignees = &us
+	}
+	refs, err := s.issueRefs(ctx, repo, labels, assignees, i.Milestone)
 	if err != nil {
 		return issues.Issue{}, err
 	}
 
-	return issues.Issue{
-		ID:    uint64(*issue.Number),
-		State: issues.State(*issue.State),
-		Title: *issue.Title,
-		Comment: issues.Comment{
-			ID:        issueDescriptionCommentID,
-			User:      ghV3User(*issue.User),
-			CreatedAt: *issue.CreatedAt,
-			Editable:  true, // You can always edit issues you've created.
-		},
-	}, nil
+	var m struct {
+		CreateIssue struct {
+			Issue githubV4Issue
+		} `graphql:"createIssue(input:$input)"`
+	}
+	input := githubv4.CreateIssueInput{
+		RepositoryID: refs.Repository,
+		Title:        githubv4.String(i.Title),
+		Body:         githubv4.NewString(githubv4.String(i.Body)),
+		LabelIDs:     refs.Labels,
+		AssigneeIDs:  refs.Assignees,
+		MilestoneID:  refs.Milestone,
+	}
+	err = s.v4(ctx).Mutate(ctx, &m, input, nil)
+	if err != nil {
+		return issues.Issue{}, err
+	}
+	return ghIssue(m.CreateIssue.Issue, refs.Viewer), nil
 }
 
 func (s service) Edit(ctx context.Context, rs issues.RepoSpec, id uint64, ir issues.IssueRequest) (issues.Issue, []issues.Event, error) {
@@ -530,18 +598,32 @@ func (s service) Edit(ctx context.Context, rs issues.RepoSpec, id uint64, ir iss
 		// TODO: Map to 400 Bad Request HTTP error.
 		return issues.Issue{}, nil, err
 	}
+	if len(ir.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Issue{}, nil, &issues.InvalidArgumentError{Field: "Fields", Reason: "custom fields are read-only"}
+	}
 	repo, err := ghRepoSpec(rs)
 	if err != nil {
 		// TODO: Map to 400 Bad Request HTTP error.
 		return issues.Issue{}, nil, err
 	}
 
-	// Fetch issue state and title before the edit, as well as current user.
+	// Fetch issue state, title, labels and milestone before the edit, as well as current user.
 	var q struct {
 		Repository struct {
 			Issue struct {
-				State githubv4.IssueState
-				Title string
+				ID     githubv4.ID
+				State  githubv4.IssueState
+				Title  string
+				Labels struct {
+					Nodes []struct {
+						Name  string
+						Color string
+					}
+				} `graphql:"labels(first:100)"`
+				Milestone *struct {
+					Title string
+				}
 			} `graphql:"issue(number:$issueNumber)"`
 		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
 		Viewer githubV4User
@@ -551,62 +633,228 @@ func (s service) Edit(ctx context.Context, rs issues.RepoSpec, id uint64, ir iss
 		"repositoryName":  githubv4.String(repo.Repo),
 		"issueNumber":     githubv4.Int(id),
 	}
-	err = s.clV4.Query(ctx, &q, variables)
+	err = s.v4(ctx).Query(ctx, &q, variables)
 	if err != nil {
 		return issues.Issue{}, nil, err
 	}
 	beforeEdit := q.Repository.Issue
 
-	ghIR := githubv3.IssueRequest{
-		Title: ir.Title,
+	input := githubv4.UpdateIssueInput{
+		ID: beforeEdit.ID,
 	}
 	if ir.State != nil {
-		ghIR.State = githubv3.String(string(*ir.State))
+		var state githubv4.IssueState
+		switch *ir.State {
+		case issues.OpenState:
+			state = githubv4.IssueStateOpen
+		case issues.ClosedState:
+			state = githubv4.IssueStateClosed
+		}
+		input.State = &state
 	}
-
-	issue, _, err := s.clV3.Issues.Edit(ctx, repo.Owner, repo.Repo, int(id), &ghIR)
+	if ir.Title != nil {
+		input.Title = githubv4.NewString(githubv4.String(*ir.Title))
+	}
+	if ir.Labels != nil || ir.Assignees != nil || ir.Milestone != nil {
+		refs, err := s.issueRefs(ctx, repo, ir.Labels, ir.Assignees, ir.Milestone)
+		if err != nil {
+			return issues.Issue{}, nil, err
+		}
+		input.LabelIDs = refs.Labels
+		input.AssigneeIDs = refs.Assignees
+		input.MilestoneID = refs.Milestone
+	}
+	var m struct {
+		UpdateIssue struct {
+			Issue githubV4Issue
+		} `graphql:"updateIssue(input:$input)"`
+	}
+	mutated := time.Now()
+	err = s.v4(ctx).Mutate(ctx, &m, input, nil)
 	if err != nil {
 		return issues.Issue{}, nil, err
 	}
+	issue := ghIssue(m.UpdateIssue.Issue, ghUser(&q.Viewer))
 
-	// GitHub API doesn't return the event that will be generated as a result, so we predict what it'll be.
-	event := issues.Event{
-		// TODO: Figure out if event ID needs to be set, and if so, how to best do that...
-		Actor:     ghUser(&q.Viewer),
-		CreatedAt: time.Now().UTC(),
+	// GitHub API doesn't return the events that were generated as a result, so we predict what they'll be.
+	// A single edit can result in multiple events, one per changed field.
+	// They're predicted in a deterministic order: state first, then title, labels and milestone.
+	newEvent := func(typ issues.EventType) issues.Event {
+		return issues.Event{
+			Actor:     ghUser(&q.Viewer),
+			CreatedAt: time.Now().UTC(),
+			Type:      typ,
+		}
 	}
-	// TODO: A single edit operation can result in multiple events, we should emit multiple events in such cases. We're currently emitting at most one event.
-	switch {
-	case ir.State != nil && *ir.State != ghIssueState(beforeEdit.State):
+	var events []issues.Event
+	if ir.State != nil && *ir.State != ghIssueState(beforeEdit.State) {
 		switch *ir.State {
 		case issues.OpenState:
-			event.Type = issues.Reopened
+			events = append(events, newEvent(issues.Reopened))
 		case issues.ClosedState:
-			event.Type = issues.Closed
+			events = append(events, newEvent(issues.Closed))
 		}
-	case ir.Title != nil && *ir.Title != beforeEdit.Title:
-		event.Type = issues.Renamed
+	}
+	if ir.Title != nil && *ir.Title != beforeEdit.Title {
+		event := newEvent(issues.Renamed)
 		event.Rename = &issues.Rename{
 			From: beforeEdit.Title,
 			To:   *ir.Title,
 		}
-	}
-	var events []issues.Event
-	if event.Type != "" {
 		events = append(events, event)
 	}
+	if ir.Labels != nil {
+		// Removed labels first, then added ones.
+		before := make(map[string]bool)
+		for _, l := range beforeEdit.Labels.Nodes {
+			before[l.Name] = true
+			if !hasLabel(issue.Labels, l.Name) {
+				event := newEvent(issues.Unlabeled)
+				event.Label = &issues.Label{Name: l.Name, Color: ghColor(l.Color)}
+				events = append(events, event)
+			}
+		}
+		for _, l := range issue.Labels {
+			if !before[l.Name] {
+				event := newEvent(issues.Labeled)
+				event.Label = &issues.Label{Name: l.Name, Color: l.Color}
+				events = append(events, event)
+			}
+		}
+	}
+	if ir.Milestone != nil && (beforeEdit.Milestone == nil || beforeEdit.Milestone.Title != ir.Milestone.Name) {
+		if beforeEdit.Milestone != nil {
+			event := newEvent(issues.Demilestoned)
+			event.Milestone = &issues.Milestone{Name: beforeEdit.Milestone.Title}
+			events = append(events, event)
+		}
+		if ir.Milestone.Name != "" {
+			event := newEvent(issues.Milestoned)
+			event.Milestone = &issues.Milestone{Name: ir.Milestone.Name}
+			events = append(events, event)
+		}
+	}
+	if len(events) > 0 {
+		err = s.reconcileEvents(ctx, repo, id, q.Viewer.DatabaseID, mutated, events)
+		if err != nil {
+			log.Println("service.Edit: failed to reconcileEvents:", err)
+		}
+	}
 
-	return issues.Issue{
-		ID:    uint64(*issue.Number),
-		State: issues.State(*issue.State),
-		Title: *issue.Title,
-		Comment: issues.Comment{
-			ID:        issueDescriptionCommentID,
-			User:      ghV3User(*issue.User),
-			CreatedAt: *issue.CreatedAt,
-			Editable:  true, // You can always edit issues you've edited.
-		},
-	}, events, nil
+	return issue, events, nil
+}
+
+// clockSkew is the largest difference between the clocks of GitHub and ours
+// that's allowed for when comparing times of events.
+const clockSkew = 10 * time.Second
+
+// reconcileEvents reconciles events predicted to be created by viewer
+// at time since or later with the most recent events in the timeline of
+// the specified issue, updating predicted events with the IDs and details
+// of matching actual events. Predicted events without a match, which can
+// happen if GitHub hasn't recorded them yet, are left unmodified.
+func (s service) reconcileEvents(ctx context.Context, repo repoSpec, id uint64, viewerID uint64, since time.Time, events []issues.Event) error {
+	type event struct { // Common fields for all events.
+		ID        string
+		Actor     *githubV4Actor
+		CreatedAt githubv4.DateTime
+	}
+	type labelEvent struct {
+		event
+		Label struct {
+			Name string
+		}
+	}
+	type milestoneEvent struct {
+		event
+		MilestoneTitle string
+	}
+	var q struct {
+		Repository struct {
+			Issue struct {
+				TimelineItems struct {
+					Nodes []struct {
+						Typename    string `graphql:"__typename"`
+						ClosedEvent struct {
+							event
+							Closer *githubV4Closer
+						} `graphql:"...on ClosedEvent"`
+						ReopenedEvent     event `graphql:"...on ReopenedEvent"`
+						RenamedTitleEvent struct {
+							event
+							CurrentTitle string
+						} `graphql:"...on RenamedTitleEvent"`
+						LabeledEvent      labelEvent     `graphql:"...on LabeledEvent"`
+						UnlabeledEvent    labelEvent     `graphql:"...on UnlabeledEvent"`
+						MilestonedEvent   milestoneEvent `graphql:"...on MilestonedEvent"`
+						DemilestonedEvent milestoneEvent `graphql:"...on DemilestonedEvent"`
+					}
+				} `graphql:"timelineItems(last:20,itemTypes:[CLOSED_EVENT,REOPENED_EVENT,RENAMED_TITLE_EVENT,LABELED_EVENT,UNLABELED_EVENT,MILESTONED_EVENT,DEMILESTONED_EVENT])"`
+			} `graphql:"issue(number:$issueNumber)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"issueNumber":     githubv4.Int(id),
+	}
+	err := s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return err
+	}
+	nodes := q.Repository.Issue.TimelineItems.Nodes
+	used := make([]bool, len(nodes))
+	since = since.Add(-clockSkew) // Allow for GitHub's clock being behind ours.
+	for i := range events {
+		// Look for the most recent unused matching event, since ours were just created.
+		for j := len(nodes) - 1; j >= 0; j-- {
+			if used[j] || ghEventType(nodes[j].Typename) != events[i].Type {
+				continue
+			}
+			var e event
+			switch events[i].Type {
+			case issues.Closed:
+				e = nodes[j].ClosedEvent.event
+			case issues.Reopened:
+				e = nodes[j].ReopenedEvent
+			case issues.Renamed:
+				if nodes[j].RenamedTitleEvent.CurrentTitle != events[i].Rename.To {
+					continue
+				}
+				e = nodes[j].RenamedTitleEvent.event
+			case issues.Labeled, issues.Unlabeled:
+				le := nodes[j].LabeledEvent
+				if events[i].Type == issues.Unlabeled {
+					le = nodes[j].UnlabeledEvent
+				}
+				if le.Label.Name != events[i].Label.Name {
+					continue
+				}
+				e = le.event
+			case issues.Milestoned, issues.Demilestoned:
+				me := nodes[j].MilestonedEvent
+				if events[i].Type == issues.Demilestoned {
+					me = nodes[j].DemilestonedEvent
+				}
+				if me.MilestoneTitle != events[i].Milestone.Name {
+					continue
+				}
+				e = me.event
+			}
+			if e.Actor == nil || e.Actor.User.DatabaseID != viewerID || e.CreatedAt.Before(since) {
+				continue
+			}
+			if events[i].Type == issues.Closed {
+				events[i].Close = s.ghClose(ctx, nodes[j].ClosedEvent.Closer)
+			}
+			used[j] = true
+			events[i].ID = ghEventID(e.ID)
+			events[i].Actor = ghActor(e.Actor)
+			events[i].CreatedAt = e.CreatedAt.Time
+			break
+		}
+	}
+	return nil
 }
 
 func (s service) EditComment(ctx context.Context, rs issues.RepoSpec, id uint64, cr issues.CommentRequest) (issues.Comment, error) {
@@ -619,208 +867,452 @@ func (s service) EditComment(ctx context.Context, rs issues.RepoSpec, id uint64,
 		return issues.Comment{}, err
 	}
 
-	if cr.ID == issueDescriptionCommentID {
-		var comment issues.Comment
+	// Fetch the issue ID, in case the comment is the issue description, as well as current user.
+	var q struct {
+		Repository struct {
+			Issue struct {
+				ID githubv4.ID
+			} `graphql:"issue(number:$issueNumber)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+		Viewer githubV4User
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"issueNumber":     githubv4.Int(id),
+	}
+	err = s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return issues.Comment{}, err
+	}
+	viewer := ghUser(&q.Viewer)
+	subjectID := q.Repository.Issue.ID
+	if cr.ID != issueDescriptionCommentID {
+		// GitHub API uses comment ID and doesn't need issue ID. Comment IDs are unique per repo (rather than per issue).
+		subjectID = githubv4.ID(base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("012:IssueComment%d", cr.ID)))) // HACK, TODO: Confirm StdEncoding vs URLEncoding.
+	}
 
-		// Apply edits.
-		if cr.Body != nil {
-			// Use Issues.Edit() API to edit comment 0 (the issue description).
-			issue, _, err := s.clV3.Issues.Edit(ctx, repo.Owner, repo.Repo, int(id), &githubv3.IssueRequest{
-				Body: cr.Body,
-			})
-			if err != nil {
-				return issues.Comment{}, err
-			}
+	var comment issues.Comment
 
-			var edited *issues.Edited
-			/* TODO: Get the actual edited information once GitHub API allows it. Can't use issue.UpdatedAt because of false positives, since it includes the entire issue, not just its comment body.
-			if !issue.UpdatedAt.Equal(*issue.CreatedAt) {
-				edited = &issues.Edited{
-					By: users.User{Login: "Someone"}, //ghV3User(*issue.Actor), // TODO: Get the actual actor once GitHub API allows it.
-					At: *issue.UpdatedAt,
-				}
-			}*/
-			// TODO: Consider setting reactions? But it's semi-expensive (to fetch all user details) and not used by app...
-			comment.ID = issueDescriptionCommentID
-			comment.User = ghV3User(*issue.User)
-			comment.CreatedAt = *issue.CreatedAt
-			comment.Edited = edited
-			comment.Body = *issue.Body
-			comment.Editable = true // You can always edit comments you've edited.
-		}
-		if cr.Reaction != nil {
-			reactionContent, err := externalizeReaction(*cr.Reaction)
+	// Apply edits.
+	if cr.Body != nil {
+		if cr.ID == issueDescriptionCommentID {
+			var m struct {
+				UpdateIssue struct {
+					Issue githubV4Issue
+				} `graphql:"updateIssue(input:$input)"`
+			}
+			input := githubv4.UpdateIssueInput{
+				ID:   subjectID,
+				Body: githubv4.NewString(githubv4.String(*cr.Body)),
+			}
+			err := s.v4(ctx).Mutate(ctx, &m, input, nil)
 			if err != nil {
 				return issues.Comment{}, err
 			}
-			// See if user has already reacted with that reaction.
-			// If not, add it. Otherwise, remove it.
-			var q struct {
-				Repository struct {
-					Issue struct {
-						ID        githubv4.ID
-						Reactions struct {
-							ViewerHasReacted githubv4.Boolean
-						} `graphql:"reactions(content:$reactionContent)"`
-					} `graphql:"issue(number:$issueNumber)"`
-				} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
-				Viewer githubV4User
+			comment = ghIssue(m.UpdateIssue.Issue, viewer).Comment
+		} else {
+			var m struct {
+				UpdateIssueComment struct {
+					IssueComment struct {
+						DatabaseID uint64
+						githubV4Comment
+					}
+				} `graphql:"updateIssueComment(input:$input)"`
 			}
-			variables := map[string]interface{}{
-				"repositoryOwner": githubv4.String(repo.Owner),
-				"repositoryName":  githubv4.String(repo.Repo),
-				"issueNumber":     githubv4.Int(id),
-				"reactionContent": reactionContent,
+			input := githubv4.UpdateIssueCommentInput{
+				ID:   subjectID,
+				Body: githubv4.String(*cr.Body),
 			}
-			err = s.clV4.Query(ctx, &q, variables)
+			err := s.v4(ctx).Mutate(ctx, &m, input, nil)
 			if err != nil {
 				return issues.Comment{}, err
 			}
+			c := m.UpdateIssueComment.IssueComment
+			comment = ghComment(c.DatabaseID, c.githubV4Comment, viewer)
+		}
+	}
+	if cr.Reaction != nil {
+		reactionContent, err := externalizeReaction(*cr.Reaction)
+		if err != nil {
+			return issues.Comment{}, err
+		}
+		comment.Reactions, err = s.toggleReaction(ctx, subjectID, reactionContent, viewer)
+		if err != nil {
+			return issues.Comment{}, err
+		}
+	}
 
-			var rgs reactionGroups
-			if !q.Repository.Issue.Reactions.ViewerHasReacted {
-				// Add reaction.
-				var m struct {
-					AddReaction struct {
-						Subject struct {
-							ReactionGroups reactionGroups
-						}
-					} `graphql:"addReaction(input:$input)"`
-				}
-				input := githubv4.AddReactionInput{
-					SubjectID: q.Repository.Issue.ID,
-					Content:   reactionContent,
-				}
-				err := s.clV4.Mutate(ctx, &m, input, nil)
-				if err != nil {
-					return issues.Comment{}, err
-				}
-				rgs = m.AddReaction.Subject.ReactionGroups
-			} else {
-				// Remove reaction.
-				var m struct {
-					RemoveReaction struct {
-						Subject struct {
-							ReactionGroups reactionGroups
-						}
-					} `graphql:"removeReaction(input:$input)"`
-				}
-				input := githubv4.RemoveReactionInput{
-					SubjectID: q.Repository.Issue.ID,
-					Content:   reactionContent,
+	return comment, nil
+}
+
+// toggleReaction adds reaction with the specified content to subject
+// if viewer hasn't already reacted with it. Otherwise, it removes it.
+// It returns the resulting reactions of subject.
+func (s service) toggleReaction(ctx context.Context, subjectID githubv4.ID, content githubv4.ReactionContent, viewer users.User) ([]reactions.Reaction, error) {
+	// See if user has already reacted with that reaction.
+	var q struct {
+		Node struct {
+			Reactable struct {
+				Reactions struct {
+					ViewerHasReacted githubv4.Boolean
+				} `graphql:"reactions(content:$reactionContent)"`
+			} `graphql:"...on Reactable"`
+		} `graphql:"node(id:$subjectID)"`
+	}
+	variables := map[string]interface{}{
+		"subjectID":       subjectID,
+		"reactionContent": content,
+	}
+	err := s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	var rgs reactionGroups
+	if !q.Node.Reactable.Reactions.ViewerHasReacted {
+		// Add reaction.
+		var m struct {
+			AddReaction struct {
+				Subject struct {
+					ReactionGroups reactionGroups
 				}
-				err := s.clV4.Mutate(ctx, &m, input, nil)
-				if err != nil {
-					return issues.Comment{}, err
+			} `graphql:"addReaction(input:$input)"`
+		}
+		input := githubv4.AddReactionInput{
+			SubjectID: subjectID,
+			Content:   content,
+		}
+		err := s.v4(ctx).Mutate(ctx, &m, input, nil)
+		if err != nil {
+			return nil, err
+		}
+		rgs = m.AddReaction.Subject.ReactionGroups
+	} else {
+		// Remove reaction.
+		var m struct {
+			RemoveReaction struct {
+				Subject struct {
+					ReactionGroups reactionGroups
 				}
-				rgs = m.RemoveReaction.Subject.ReactionGroups
-			}
-			// TODO: Consider setting other fields? But it's semi-expensive (another API call) and not used by app...
-			//       Actually, now that using GraphQL, no longer that expensive (can be same API call).
-			comment.Reactions = ghReactions(rgs, ghUser(&q.Viewer))
+			} `graphql:"removeReaction(input:$input)"`
 		}
-
-		return comment, nil
+		input := githubv4.RemoveReactionInput{
+			SubjectID: subjectID,
+			Content:   content,
+		}
+		err := s.v4(ctx).Mutate(ctx, &m, input, nil)
+		if err != nil {
+			return nil, err
+		}
+		rgs = m.RemoveReaction.Subject.ReactionGroups
 	}
+	return ghReactions(rgs, viewer), nil
+}
 
-	var comment issues.Comment
+// issueRefs are node IDs of a repository, and of its labels, users and milestone,
+// as needed to create or update an issue via GitHub GraphQL API v4.
+type issueRefs struct {
+	Repository githubv4.ID
+	Labels     *[]githubv4.ID
+	Assignees  *[]githubv4.ID
+	Milestone  *githubv4.ID // Milestone points to a nil ID to clear the milestone.
+	Viewer     users.User
+}
 
-	// Apply edits.
-	if cr.Body != nil {
-		// GitHub API uses comment ID and doesn't need issue ID. Comment IDs are unique per repo (rather than per issue).
-		ghComment, _, err := s.clV3.Issues.EditComment(ctx, repo.Owner, repo.Repo, int64(cr.ID), &githubv3.IssueComment{
-			Body: cr.Body,
-		})
+// issueRefs looks up node IDs of repo, and of labels, assignees and milestone
+// that aren't nil. A milestone with an empty name means no milestone.
+func (s service) issueRefs(ctx context.Context, repo repoSpec, labels *[]issues.Label, assignees *[]users.UserSpec, milestone *issues.Milestone) (issueRefs, error) {
+	var q struct {
+		Repository struct {
+			ID githubv4.ID
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+		Viewer githubV4User
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+	}
+	err := s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return issueRefs{}, err
+	}
+	refs := issueRefs{
+		Repository: q.Repository.ID,
+		Viewer:     ghUser(&q.Viewer),
+	}
+	if labels != nil {
+		ids, err := s.labelIDs(ctx, repo, *labels)
 		if err != nil {
-			return issues.Comment{}, err
+			return issueRefs{}, err
 		}
-
-		var edited *issues.Edited
-		if !ghComment.UpdatedAt.Equal(*ghComment.CreatedAt) {
-			edited = &issues.Edited{
-				By: users.User{Login: "Someone"}, //ghV3User(*ghComment.Actor), // TODO: Get the actual actor once GitHub API allows it.
-				At: *ghComment.UpdatedAt,
+		refs.Labels = &ids
+	}
+	if assignees != nil {
+		ids, err := s.assigneeIDs(ctx, repo, *assignees)
+		if err != nil {
+			return issueRefs{}, err
+		}
+		refs.Assignees = &ids
+	}
+	if milestone != nil {
+		refs.Milestone = new(githubv4.ID) // Nil ID, which clears the milestone.
+		if milestone.Name != "" {
+			*refs.Milestone, err = s.milestoneID(ctx, repo, milestone.Name)
+			if err != nil {
+				return issueRefs{}, err
 			}
 		}
-		// TODO: Consider setting reactions? But it's semi-expensive (to fetch all user details) and not used by app...
-		comment.ID = uint64(*ghComment.ID)
-		comment.User = ghV3User(*ghComment.User)
-		comment.CreatedAt = *ghComment.CreatedAt
-		comment.Edited = edited
-		comment.Body = *ghComment.Body
-		comment.Editable = true // You can always edit comments you've edited.
 	}
-	if cr.Reaction != nil {
-		reactionContent, err := externalizeReaction(*cr.Reaction)
+	return refs, nil
+}
+
+// labelIDs looks up node IDs of labels in repo.
+// It goes through pages of the repository labels until all are found.
+func (s service) labelIDs(ctx context.Context, repo repoSpec, labels []issues.Label) ([]githubv4.ID, error) {
+	found := make(map[string]githubv4.ID) // Label name -> node ID.
+	wanted := make(map[string]bool)
+	for _, l := range labels {
+		wanted[l.Name] = true
+	}
+	var q struct {
+		Repository struct {
+			Labels struct {
+				Nodes []struct {
+					ID   githubv4.ID
+					Name string
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"labels(first:100,after:$labelsCursor)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"labelsCursor":    (*githubv4.String)(nil), // Start from beginning.
+	}
+	for len(found) < len(wanted) {
+		err := s.v4(ctx).Query(ctx, &q, variables)
 		if err != nil {
-			return issues.Comment{}, err
+			return nil, err
 		}
-		commentID := githubv4.ID(base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("012:IssueComment%d", cr.ID)))) // HACK, TODO: Confirm StdEncoding vs URLEncoding.
-		// See if user has already reacted with that reaction.
-		// If not, add it. Otherwise, remove it.
-		var q struct {
-			Node struct {
-				IssueComment struct {
-					Reactions struct {
-						ViewerHasReacted githubv4.Boolean
-					} `graphql:"reactions(content:$reactionContent)"`
-				} `graphql:"...on IssueComment"`
-			} `graphql:"node(id:$commentID)"`
-			Viewer githubV4User
-		}
-		variables := map[string]interface{}{
-			"commentID":       commentID,
-			"reactionContent": reactionContent,
-		}
-		err = s.clV4.Query(ctx, &q, variables)
-		if err != nil {
-			return issues.Comment{}, err
+		for _, n := range q.Repository.Labels.Nodes {
+			if wanted[n.Name] {
+				found[n.Name] = n.ID
+			}
 		}
+		if !q.Repository.Labels.PageInfo.HasNextPage {
+			break
+		}
+		variables["labelsCursor"] = githubv4.NewString(q.Repository.Labels.PageInfo.EndCursor)
+	}
+	ids := []githubv4.ID{}
+	for _, l := range labels {
+		id, ok := found[l.Name]
+		if !ok {
+			// TODO: Map to 400 Bad Request HTTP error.
+			return nil, &issues.InvalidArgumentError{Field: "Labels", Reason: fmt.Sprintf("no such label %q", l.Name)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 
-		var rgs reactionGroups
-		if !q.Node.IssueComment.Reactions.ViewerHasReacted {
-			// Add reaction.
-			var m struct {
-				AddReaction struct {
-					Subject struct {
-						ReactionGroups reactionGroups
-					}
-				} `graphql:"addReaction(input:$input)"`
-			}
-			input := githubv4.AddReactionInput{
-				SubjectID: commentID,
-				Content:   reactionContent,
-			}
-			err := s.clV4.Mutate(ctx, &m, input, nil)
-			if err != nil {
-				return issues.Comment{}, err
-			}
-			rgs = m.AddReaction.Subject.ReactionGroups
-		} else {
-			// Remove reaction.
-			var m struct {
-				RemoveReaction struct {
-					Subject struct {
-						ReactionGroups reactionGroups
-					}
-				} `graphql:"removeReaction(input:$input)"`
-			}
-			input := githubv4.RemoveReactionInput{
-				SubjectID: commentID,
-				Content:   reactionContent,
+// assigneeIDs looks up node IDs of assignees in repo.
+// It goes through pages of the repository assignable users until all are found.
+func (s service) assigneeIDs(ctx context.Context, repo repoSpec, assignees []users.UserSpec) ([]githubv4.ID, error) {
+	found := make(map[uint64]githubv4.ID) // User database ID -> node ID.
+	wanted := make(map[uint64]bool)
+	for _, u := range assignees {
+		if u.Domain != "github.com" {
+			// TODO: Map to 400 Bad Request HTTP error.
+			return nil, &issues.InvalidArgumentError{Field: "Assignees", Reason: fmt.Sprintf("user %d@%s can't be assigned", u.ID, u.Domain)}
+		}
+		wanted[u.ID] = true
+	}
+	var q struct {
+		Repository struct {
+			AssignableUsers struct {
+				Nodes []struct {
+					ID         githubv4.ID
+					DatabaseID uint64
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"assignableUsers(first:100,after:$usersCursor)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"usersCursor":     (*githubv4.String)(nil), // Start from beginning.
+	}
+	for len(found) < len(wanted) {
+		err := s.v4(ctx).Query(ctx, &q, variables)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range q.Repository.AssignableUsers.Nodes {
+			if wanted[n.DatabaseID] {
+				found[n.DatabaseID] = n.ID
 			}
-			err := s.clV4.Mutate(ctx, &m, input, nil)
-			if err != nil {
-				return issues.Comment{}, err
+		}
+		if !q.Repository.AssignableUsers.PageInfo.HasNextPage {
+			break
+		}
+		variables["usersCursor"] = githubv4.NewString(q.Repository.AssignableUsers.PageInfo.EndCursor)
+	}
+	ids := []githubv4.ID{}
+	for _, u := range assignees {
+		id, ok := found[u.ID]
+		if !ok {
+			// TODO: Map to 400 Bad Request HTTP error.
+			return nil, &issues.InvalidArgumentError{Field: "Assignees", Reason: fmt.Sprintf("user %d@%s can't be assigned", u.ID, u.Domain)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// milestoneID looks up the node ID of the milestone with title in repo.
+// It goes through pages of the repository milestones until it's found.
+func (s service) milestoneID(ctx context.Context, repo repoSpec, title string) (githubv4.ID, error) {
+	var q struct {
+		Repository struct {
+			Milestones struct {
+				Nodes []struct {
+					ID    githubv4.ID
+					Title string
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"milestones(first:100,after:$milestonesCursor)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner":  githubv4.String(repo.Owner),
+		"repositoryName":   githubv4.String(repo.Repo),
+		"milestonesCursor": (*githubv4.String)(nil), // Start from beginning.
+	}
+	for {
+		err := s.v4(ctx).Query(ctx, &q, variables)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range q.Repository.Milestones.Nodes {
+			if n.Title == title {
+				return n.ID, nil
 			}
-			rgs = m.RemoveReaction.Subject.ReactionGroups
 		}
-		// TODO: Consider setting other fields? But it's semi-expensive (another API call) and not used by app...
-		//       Actually, now that using GraphQL, no longer that expensive (can be same API call).
-		comment.Reactions = ghReactions(rgs, ghUser(&q.Viewer))
+		if !q.Repository.Milestones.PageInfo.HasNextPage {
+			break
+		}
+		variables["milestonesCursor"] = githubv4.NewString(q.Repository.Milestones.PageInfo.EndCursor)
 	}
+	// TODO: Map to 400 Bad Request HTTP error.
+	return nil, &issues.InvalidArgumentError{Field: "Milestone", Reason: fmt.Sprintf("no such milestone %q", title)}
+}
 
-	return comment, nil
+// githubV4Comment is a comment, or an issue description,
+// with all fields needed to populate an issues.Comment.
+type githubV4Comment struct {
+	Author          *githubV4Actor
+	PublishedAt     githubv4.DateTime
+	LastEditedAt    *githubv4.DateTime
+	Editor          *githubV4Actor
+	Body            string
+	ReactionGroups  reactionGroups
+	ViewerCanUpdate bool
+}
+
+// ghComment converts a GitHub comment with the specified ID into issues.Comment.
+func ghComment(id uint64, comment githubV4Comment, viewer users.User) issues.Comment {
+	var edited *issues.Edited
+	if comment.LastEditedAt != nil {
+		edited = &issues.Edited{
+			By: ghActor(comment.Editor),
+			At: comment.LastEditedAt.Time,
+		}
+	}
+	return issues.Comment{
+		ID:        id,
+		User:      ghActor(comment.Author),
+		CreatedAt: comment.PublishedAt.Time,
+		Edited:    edited,
+		Body:      comment.Body,
+		Reactions: ghReactions(comment.ReactionGroups, viewer),
+		Editable:  comment.ViewerCanUpdate,
+	}
+}
+
+// githubV4Issue is an issue with all fields needed to populate an issues.Issue,
+// other than custom fields.
+type githubV4Issue struct {
+	Number uint64
+	State  githubv4.IssueState
+	Title  string
+	Labels struct {
+		Nodes []struct {
+			Name  string
+			Color string
+		}
+	} `graphql:"labels(first:100)"`
+	Assignees struct {
+		Nodes []*githubV4User
+	} `graphql:"assignees(first:10)"`
+	Milestone *struct {
+		Title string
+	}
+	githubV4Comment
+	Comments struct {
+		TotalCount int
+	}
+}
+
+// ghIssue converts a GitHub issue into issues.Issue.
+func ghIssue(issue githubV4Issue, viewer users.User) issues.Issue {
+	var labels []issues.Label
+	for _, l := range issue.Labels.Nodes {
+		labels = append(labels, issues.Label{
+			Name:  l.Name,
+			Color: ghColor(l.Color),
+		})
+	}
+	var assignees []users.User
+	for _, u := range issue.Assignees.Nodes {
+		assignees = append(assignees, ghUser(u))
+	}
+	var milestone *issues.Milestone
+	if issue.Milestone != nil {
+		milestone = &issues.Milestone{Name: issue.Milestone.Title}
+	}
+	return issues.Issue{
+		ID:        issue.Number,
+		State:     ghIssueState(issue.State),
+		Title:     issue.Title,
+		Labels:    labels,
+		Assignees: assignees,
+		Milestone: milestone,
+		Comment:   ghComment(issueDescriptionCommentID, issue.githubV4Comment, viewer),
+		Replies:   issue.Comments.TotalCount,
+	}
+}
+
+// hasLabel reports whether labels include a label with the specified name.
+func hasLabel(labels []issues.Label, name string) bool {
+	for _, l := range labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
 }
 
 type repoSpec struct {
@@ -889,18 +1381,54 @@ func ghUser(user *githubV4User) users.User {
 	}
 }
 
-func ghV3User(user githubv3.User) users.User {
-	if *user.ID == 0 {
-		return ghost // Deleted user, replace with https://github.com/ghost.
+type githubV4Closer struct {
+	Typename    string `graphql:"__typename"`
+	PullRequest struct {
+		State      githubv4.PullRequestState
+		Title      string
+		Repository struct {
+			Owner struct{ Login string }
+			Name  string
+		}
+		Number uint64
+	} `graphql:"...on PullRequest"`
+	Commit struct {
+		OID     string
+		Message string
+		Author  struct {
+			AvatarURL string `graphql:"avatarUrl(size:96)"`
+		}
+		URL string
+	} `graphql:"...on Commit"`
+}
+
+// ghClose converts the closer of a GitHub ClosedEvent into issues.Close.
+func (s service) ghClose(ctx context.Context, closer *githubV4Closer) issues.Close {
+	if closer == nil {
+		return issues.Close{}
 	}
-	return users.User{
-		UserSpec: users.UserSpec{
-			ID:     uint64(*user.ID),
-			Domain: "github.com",
-		},
-		Login:     *user.Login,
-		AvatarURL: *user.AvatarURL,
-		HTMLURL:   *user.HTMLURL,
+	switch closer.Typename {
+	case "PullRequest":
+		pr := closer.PullRequest
+		return issues.Close{
+			Closer: issues.Change{
+				State:   ghPRState(pr.State),
+				Title:   pr.Title,
+				HTMLURL: s.rtr.PullRequestURL(ctx, pr.Repository.Owner.Login, pr.Repository.Name, pr.Number),
+			},
+		}
+	case "Commit":
+		c := closer.Commit
+		return issues.Close{
+			Closer: issues.Commit{
+				SHA:             c.OID,
+				Message:         c.Message,
+				AuthorAvatarURL: c.Author.AvatarURL,
+				HTMLURL:         c.URL,
+			},
+		}
+	default:
+		return issues.Close{}
 	}
 }
 
@@ -953,13 +1481,37 @@ func ghEventType(typename string) issues.EventType {
 		return issues.Labeled
 	case "UnlabeledEvent":
 		return issues.Unlabeled
+	case "MilestonedEvent":
+		return issues.Milestoned
+	case "DemilestonedEvent":
+		return issues.Demilestoned
 	case "CommentDeletedEvent":
 		return issues.CommentDeleted
+	case "CrossReferencedEvent", "ReferencedEvent":
+		return issues.CrossReferenced
 	default:
 		return issues.EventType(typename)
 	}
 }
 
+// ghEventID returns a stable event ID for the GitHub event with the given global node ID.
+// Legacy node IDs, like base64 of "011:ClosedEvent1234", embed the event's database ID,
+// which is used when available, so that IDs match "#event-1234" anchors on github.com.
+// Otherwise, a hash of the node ID is used.
+func ghEventID(nodeID string) uint64 {
+	if b, err := base64.StdEncoding.DecodeString(nodeID); err == nil {
+		if i := strings.IndexByte(string(b), ':'); i != -1 {
+			digits := strings.TrimLeftFunc(string(b[i+1:]), func(r rune) bool { return r < '0' || r > '9' })
+			if id, err := strconv.ParseUint(digits, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(nodeID))
+	return h.Sum64()
+}
+
 // ghColor converts a GitHub color hex string like "ff0000"
 // into an issues.RGB value.
 func ghColor(hex string) issues.RGB {
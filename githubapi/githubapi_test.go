@@ -0,0 +1,312 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dmitri.shuralyov.com/state"
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+func TestGHEventID(t *testing.T) {
+	legacy := base64.StdEncoding.EncodeToString([]byte("011:ClosedEvent1234567"))
+	if got, want := ghEventID(legacy), uint64(1234567); got != want {
+		t.Errorf("ghEventID(%q) = %d, want %d", legacy, got, want)
+	}
+
+	// Other node IDs get a stable, non-zero ID.
+	const nodeID = "CE_lADOAqDBLM5Ld4nKzwAAAAE"
+	if got := ghEventID(nodeID); got == 0 || got != ghEventID(nodeID) {
+		t.Errorf("ghEventID(%q) = %d, want a stable non-zero ID", nodeID, got)
+	}
+	if ghEventID(nodeID) == ghEventID(nodeID+"x") {
+		t.Error("ghEventID returned the same ID for different node IDs")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	var input map[string]interface{}
+	transport := graphQLTransport(func(query string, variables map[string]interface{}) string {
+		switch {
+		case strings.HasPrefix(query, "mutation"):
+			input = variables["input"].(map[string]interface{})
+			return `{"createIssue":{"issue":{
+				"number":7,"state":"OPEN","title":"Title",
+				"labels":{"nodes":[{"name":"bug","color":"ff0000"}]},
+				"assignees":{"nodes":[]},"milestone":null,
+				"author":{"databaseId":1,"login":"gopher"},"publishedAt":"2018-01-01T00:00:00Z",
+				"lastEditedAt":null,"editor":null,"body":"Body","reactionGroups":[],"viewerCanUpdate":true,
+				"comments":{"totalCount":0}}}}`
+		case strings.Contains(query, "labels("):
+			return `{"repository":{"labels":{"nodes":[{"id":"L1","name":"bug"},{"id":"L2","name":"docs"}],"pageInfo":{"endCursor":"","hasNextPage":false}}}}`
+		default:
+			return `{"repository":{"id":"R1"},"viewer":{"databaseId":1,"login":"gopher"}}`
+		}
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil)
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+
+	issue, err := s.Create(context.Background(), repo, issues.Issue{
+		Title:   "Title",
+		Labels:  []issues.Label{{Name: "bug"}},
+		Comment: issues.Comment{Body: "Body"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := input["repositoryId"], "R1"; got != want {
+		t.Errorf("got repositoryId %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprint(input["labelIds"]), "[L1]"; got != want {
+		t.Errorf("got labelIds %v, want %v", got, want)
+	}
+	if got, want := issue.Body, "Body"; got != want {
+		t.Errorf("got Body %q, want %q", got, want)
+	}
+	if len(issue.Labels) != 1 || issue.Labels[0] != (issues.Label{Name: "bug", Color: issues.RGB{R: 0xff}}) {
+		t.Errorf("got Labels %v, want [bug]", issue.Labels)
+	}
+	if !issue.Editable || issue.User.Login != "gopher" {
+		t.Errorf("got Editable %v and User %q, want true and gopher", issue.Editable, issue.User.Login)
+	}
+
+	// Unknown labels are rejected before anything is created.
+	input = nil
+	_, err = s.Create(context.Background(), repo, issues.Issue{
+		Title:  "Title",
+		Labels: []issues.Label{{Name: "nope"}},
+	})
+	if _, ok := err.(*issues.InvalidArgumentError); !ok {
+		t.Errorf("got error %v, want *issues.InvalidArgumentError", err)
+	}
+	if input != nil {
+		t.Error("issue was created with an unknown label")
+	}
+}
+
+func TestIssueRefsPagination(t *testing.T) {
+	// Each connection has two pages, with one node each.
+	pages := map[string][2]string{
+		"labels":          {`{"id":"L1","name":"bug"}`, `{"id":"L2","name":"docs"}`},
+		"assignableUsers": {`{"id":"U1","databaseId":1}`, `{"id":"U2","databaseId":2}`},
+		"milestones":      {`{"id":"M1","title":"Go1.10"}`, `{"id":"M2","title":"Go1.11"}`},
+	}
+	var requests int
+	transport := graphQLTransport(func(query string, variables map[string]interface{}) string {
+		requests++
+		for conn, nodes := range pages {
+			if !strings.Contains(query, conn+"(") {
+				continue
+			}
+			page := `{"nodes":[` + nodes[0] + `],"pageInfo":{"endCursor":"c1","hasNextPage":true}}`
+			for _, cursor := range variables {
+				if cursor == "c1" {
+					page = `{"nodes":[` + nodes[1] + `],"pageInfo":{"endCursor":"c2","hasNextPage":false}}`
+				}
+			}
+			return `{"repository":{"` + conn + `":` + page + `}}`
+		}
+		return `{"repository":{"id":"R1"},"viewer":{"databaseId":1,"login":"gopher"}}`
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil).(service)
+	repo := repoSpec{Owner: "owner", Repo: "repo"}
+
+	// Nodes on later pages are found.
+	refs, err := s.issueRefs(context.Background(), repo,
+		&[]issues.Label{{Name: "docs"}, {Name: "bug"}},
+		&[]users.UserSpec{{ID: 2, Domain: "github.com"}},
+		&issues.Milestone{Name: "Go1.11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprintf("%v %v %v", *refs.Labels, *refs.Assignees, *refs.Milestone), "[L2 L1] [U2] M2"; got != want {
+		t.Errorf("got refs %v, want %v", got, want)
+	}
+	if requests != 7 {
+		t.Errorf("got %d requests, want 7", requests)
+	}
+
+	// Pages aren't fetched once all nodes are found.
+	requests = 0
+	_, err = s.issueRefs(context.Background(), repo, &[]issues.Label{{Name: "bug"}}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+
+	// Nodes that don't exist on any page are rejected.
+	for _, tc := range []struct {
+		labels    *[]issues.Label
+		assignees *[]users.UserSpec
+		milestone *issues.Milestone
+	}{
+		{labels: &[]issues.Label{{Name: "nope"}}},
+		{assignees: &[]users.UserSpec{{ID: 3, Domain: "github.com"}}},
+		{assignees: &[]users.UserSpec{{ID: 1, Domain: "example.org"}}},
+		{milestone: &issues.Milestone{Name: "Go2"}},
+	} {
+		_, err := s.issueRefs(context.Background(), repo, tc.labels, tc.assignees, tc.milestone)
+		if _, ok := err.(*issues.InvalidArgumentError); !ok {
+			t.Errorf("got error %v, want *issues.InvalidArgumentError", err)
+		}
+	}
+}
+
+func TestEditReconcileEvents(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	node := func(typename, id string, actor int, createdAt time.Time, extra string) string {
+		return fmt.Sprintf(`{"__typename":%q,"id":%q,"actor":{"databaseId":%d,"login":"gopher"},"createdAt":%q%s}`,
+			typename, id, actor, createdAt.Format(time.RFC3339), extra)
+	}
+	var timeline []string
+	transport := graphQLTransport(func(query string, _ map[string]interface{}) string {
+		switch {
+		case strings.HasPrefix(query, "mutation"):
+			return `{"updateIssue":{"issue":{
+				"number":7,"state":"CLOSED","title":"New",
+				"labels":{"nodes":[]},"assignees":{"nodes":[]},"milestone":null,
+				"author":{"databaseId":1,"login":"gopher"},"publishedAt":"2018-01-01T00:00:00Z",
+				"lastEditedAt":null,"editor":null,"body":"Body","reactionGroups":[],"viewerCanUpdate":true,
+				"comments":{"totalCount":0}}}}`
+		case strings.Contains(query, "timelineItems("):
+			return `{"repository":{"issue":{"timelineItems":{"nodes":[` + strings.Join(timeline, ",") + `]}}}}`
+		default:
+			return `{"repository":{"issue":{"id":"I7","state":"OPEN","title":"Old","labels":{"nodes":[]},"milestone":null}},"viewer":{"databaseId":1,"login":"gopher"}}`
+		}
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil)
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+	closed, title := issues.ClosedState, "New"
+
+	for _, tc := range []struct {
+		name     string
+		timeline []string
+		want     []string // Node IDs of the events the predicted ones are reconciled with.
+	}{
+		{
+			name: "recorded",
+			timeline: []string{
+				node("ClosedEvent", "CE_old", 1, now.Add(-time.Hour), `,"closer":null`),
+				node("ClosedEvent", "CE_new", 1, now, `,"closer":null`),
+				node("RenamedTitleEvent", "RTE_new", 1, now, `,"currentTitle":"New"`),
+			},
+			want: []string{"CE_new", "RTE_new"},
+		},
+		{
+			// GitHub hasn't recorded the events yet, so older events of
+			// the same type aren't mistaken for them.
+			name: "not recorded",
+			timeline: []string{
+				node("ClosedEvent", "CE_old", 1, now.Add(-time.Hour), `,"closer":null`),
+				node("RenamedTitleEvent", "RTE_old", 1, now.Add(-time.Hour), `,"currentTitle":"New"`),
+			},
+			want: []string{"", ""},
+		},
+		{
+			name: "other actor",
+			timeline: []string{
+				node("ClosedEvent", "CE_new", 2, now, `,"closer":null`),
+				node("RenamedTitleEvent", "RTE_new", 1, now, `,"currentTitle":"New"`),
+			},
+			want: []string{"", "RTE_new"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			timeline = tc.timeline
+			_, events, err := s.Edit(context.Background(), repo, 7, issues.IssueRequest{State: &closed, Title: &title})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 2 || events[0].Type != issues.Closed || events[1].Type != issues.Renamed {
+				t.Fatalf("got events %+v, want closed and renamed events", events)
+			}
+			for i, e := range events {
+				var want uint64
+				if tc.want[i] != "" {
+					want = ghEventID(tc.want[i])
+				}
+				if e.ID != want {
+					t.Errorf("%v event: got ID %d, want %d (of %q)", e.Type, e.ID, want, tc.want[i])
+				}
+				if want != 0 && !e.CreatedAt.Equal(now) {
+					t.Errorf("%v event: got CreatedAt %v, want %v", e.Type, e.CreatedAt, now)
+				}
+			}
+		})
+	}
+}
+
+// graphQLTransport returns a transport that responds to GraphQL requests
+// with the data that respond returns for their query and variables.
+func graphQLTransport(respond func(query string, variables map[string]interface{}) string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"data":` + respond(body.Query, body.Variables) + `}`)),
+			Request:    req,
+		}, nil
+	}
+}
+
+func TestListTimelineCrossReferences(t *testing.T) {
+	const actor = `"actor":{"databaseId":1,"login":"gopher"},"createdAt":"2018-01-01T00:00:00Z"`
+	const repository = `"repository":{"owner":{"login":"other"},"name":"repo"}`
+	transport := graphQLTransport(func(string, map[string]interface{}) string {
+		return `{"repository":{"issue":{
+			"author":{"databaseId":1,"login":"gopher"},"publishedAt":"2018-01-01T00:00:00Z",
+			"lastEditedAt":null,"editor":null,"body":"Body","reactionGroups":[],"viewerCanUpdate":true,
+			"timeline":{"nodes":[
+				{"__typename":"CrossReferencedEvent","id":"CRE_1",` + actor + `,"url":"https://github.com/owner/repo/issues/7#ref-issue-3",
+					"source":{"__typename":"Issue",` + repository + `,"number":3,"title":"Other issue"}},
+				{"__typename":"CrossReferencedEvent","id":"CRE_2",` + actor + `,"url":"https://github.com/owner/repo/issues/7#ref-pullrequest-4",
+					"source":{"__typename":"PullRequest","state":"MERGED","title":"Fix it",` + repository + `,"number":4}},
+				{"__typename":"ReferencedEvent","id":"RE_1",` + actor + `,
+					"commit":{"oid":"abc123","message":"Fix #7.","author":{"avatarUrl":"https://example.com/avatar"},"url":"https://github.com/owner/repo/commit/abc123"}},
+				{"__typename":"ReferencedEvent","id":"RE_2",` + actor + `,"commit":null}
+			],"pageInfo":{"endCursor":"","hasNextPage":false}}}},
+			"viewer":{"databaseId":1,"login":"gopher"}}`
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil)
+	timeline, err := s.(issues.TimelineLister).ListTimeline(context.Background(), issues.RepoSpec{URI: "github.com/owner/repo"}, 7, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inaccessible commits are skipped.
+	var got []interface{}
+	for _, item := range timeline[1:] {
+		e, ok := item.(issues.Event)
+		if !ok || e.Type != issues.CrossReferenced || e.CrossReference == nil {
+			t.Fatalf("got timeline item %+v, want a CrossReferenced event", item)
+		}
+		got = append(got, e.CrossReference.Source)
+	}
+	want := []interface{}{
+		issues.IssueRef{Repo: issues.RepoSpec{URI: "github.com/other/repo"}, ID: 3, Title: "Other issue", HTMLURL: "https://github.com/owner/repo/issues/7#ref-issue-3"},
+		issues.Change{State: state.ChangeMerged, Title: "Fix it", HTMLURL: "https://github.com/other/repo/pull/4"},
+		issues.Commit{SHA: "abc123", Message: "Fix #7.", AuthorAvatarURL: "https://example.com/avatar", HTMLURL: "https://github.com/owner/repo/commit/abc123"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got sources %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package githubapi
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+)
+
+// ListLabels implements issues.LabelManager.
+func (s service) ListLabels(ctx context.Context, rs issues.RepoSpec) ([]issues.Label, error) {
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, err
+	}
+	var q struct {
+		Repository struct {
+			Labels struct {
+				Nodes    []githubV4Label
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"labels(first:100,after:$labelsCursor)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"labelsCursor":    (*githubv4.String)(nil), // Start from beginning.
+	}
+	var labels []issues.Label
+	for {
+		err := s.v4(ctx).Query(ctx, &q, variables)
+		if err != nil {
+			return labels, err
+		}
+		for _, l := range q.Repository.Labels.Nodes {
+			labels = append(labels, ghLabel(l))
+		}
+		if !q.Repository.Labels.PageInfo.HasNextPage {
+			break
+		}
+		variables["labelsCursor"] = githubv4.NewString(q.Repository.Labels.PageInfo.EndCursor)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+// CreateLabel implements issues.LabelManager.
+func (s service) CreateLabel(ctx context.Context, rs issues.RepoSpec, l issues.Label) (issues.Label, error) {
+	if err := l.Validate(); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Label{}, err
+	}
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Label{}, err
+	}
+	var q struct {
+		Repository struct {
+			ID githubv4.ID
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+	}
+	err = s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	var m struct {
+		CreateLabel struct {
+			Label githubV4Label
+		} `graphql:"createLabel(input:$input)"`
+	}
+	input := githubv4.CreateLabelInput{
+		RepositoryID: q.Repository.ID,
+		Name:         githubv4.String(l.Name),
+		Color:        githubv4.String(ghColorHex(l.Color)),
+		Description:  githubv4.NewString(githubv4.String(l.Description)),
+	}
+	err = s.v4(ctx).Mutate(ctx, &m, input, nil)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	return ghLabel(m.CreateLabel.Label), nil
+}
+
+// EditLabel implements issues.LabelManager.
+// GitHub updates renamed and recolored labels on issues that have them.
+func (s service) EditLabel(ctx context.Context, rs issues.RepoSpec, name string, lr issues.LabelRequest) (issues.Label, error) {
+	if err := lr.Validate(); err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Label{}, err
+	}
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return issues.Label{}, err
+	}
+	labelID, err := s.labelID(ctx, repo, name)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	var m struct {
+		UpdateLabel struct {
+			Label githubV4Label
+		} `graphql:"updateLabel(input:$input)"`
+	}
+	input := githubv4.UpdateLabelInput{
+		ID: labelID,
+	}
+	if lr.Name != nil {
+		input.Name = githubv4.NewString(githubv4.String(*lr.Name))
+	}
+	if lr.Color != nil {
+		input.Color = githubv4.NewString(githubv4.String(ghColorHex(*lr.Color)))
+	}
+	if lr.Description != nil {
+		input.Description = githubv4.NewString(githubv4.String(*lr.Description))
+	}
+	err = s.v4(ctx).Mutate(ctx, &m, input, nil)
+	if err != nil {
+		return issues.Label{}, err
+	}
+	return ghLabel(m.UpdateLabel.Label), nil
+}
+
+// DeleteLabel implements issues.LabelManager.
+// GitHub removes deleted labels from issues that have them.
+func (s service) DeleteLabel(ctx context.Context, rs issues.RepoSpec, name string) error {
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return err
+	}
+	labelID, err := s.labelID(ctx, repo, name)
+	if err != nil {
+		return err
+	}
+	var m struct {
+		DeleteLabel struct {
+			ClientMutationID *string
+		} `graphql:"deleteLabel(input:$input)"`
+	}
+	input := githubv4.DeleteLabelInput{
+		ID: labelID,
+	}
+	return s.v4(ctx).Mutate(ctx, &m, input, nil)
+}
+
+// labelID returns the node ID of the label with the specified name in repo.
+func (s service) labelID(ctx context.Context, repo repoSpec, name string) (githubv4.ID, error) {
+	var q struct {
+		Repository struct {
+			Label *struct {
+				ID githubv4.ID
+			} `graphql:"label(name:$labelName)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"labelName":       githubv4.String(name),
+	}
+	err := s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return nil, err
+	}
+	if q.Repository.Label == nil {
+		return nil, os.ErrNotExist
+	}
+	return q.Repository.Label.ID, nil
+}
+
+type githubV4Label struct {
+	Name        string
+	Color       string
+	Description *string
+}
+
+func ghLabel(l githubV4Label) issues.Label {
+	label := issues.Label{
+		Name:  l.Name,
+		Color: ghColor(l.Color),
+	}
+	if l.Description != nil {
+		label.Description = *l.Description
+	}
+	return label
+}
+
+// ghColorHex converts an issues.RGB value into
+// a GitHub color hex string like "ff0000".
+func ghColorHex(c issues.RGB) string {
+	return c.HexString()[1:]
+}
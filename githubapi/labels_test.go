@@ -0,0 +1,100 @@
+package githubapi
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+)
+
+func TestListLabels(t *testing.T) {
+	var cursors []interface{}
+	transport := graphQLTransport(func(query string, variables map[string]interface{}) string {
+		cursors = append(cursors, variables["labelsCursor"])
+		if variables["labelsCursor"] == nil {
+			return `{"repository":{"labels":{"nodes":[{"name":"docs","color":"0075ca","description":null}],"pageInfo":{"endCursor":"C1","hasNextPage":true}}}}`
+		}
+		return `{"repository":{"labels":{"nodes":[{"name":"bug","color":"d73a4a","description":"Something isn't working."}],"pageInfo":{"endCursor":"C2","hasNextPage":false}}}}`
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil).(issues.LabelManager)
+
+	ls, err := s.ListLabels(context.Background(), issues.RepoSpec{URI: "github.com/owner/repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Labels of all pages are listed, sorted by name.
+	want := []issues.Label{
+		{Name: "bug", Color: issues.RGB{R: 0xd7, G: 0x3a, B: 0x4a}, Description: "Something isn't working."},
+		{Name: "docs", Color: issues.RGB{R: 0x00, G: 0x75, B: 0xca}},
+	}
+	if !reflect.DeepEqual(ls, want) {
+		t.Errorf("got labels %+v, want %+v", ls, want)
+	}
+	if wantCursors := []interface{}{nil, "C1"}; !reflect.DeepEqual(cursors, wantCursors) {
+		t.Errorf("got cursors %v, want %v", cursors, wantCursors)
+	}
+}
+
+func TestGHColorHex(t *testing.T) {
+	for _, tc := range []struct {
+		in   issues.RGB
+		want string
+	}{
+		{issues.RGB{}, "000000"},
+		{issues.RGB{R: 0xff}, "ff0000"},
+		{issues.RGB{R: 0x0a, G: 0xb0, B: 0x0c}, "0ab00c"},
+		{issues.RGB{R: 0xff, G: 0xff, B: 0xff}, "ffffff"},
+	} {
+		if got := ghColorHex(tc.in); got != tc.want {
+			t.Errorf("ghColorHex(%+v): got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEditLabel(t *testing.T) {
+	var inputs []map[string]interface{}
+	transport := graphQLTransport(func(query string, variables map[string]interface{}) string {
+		switch {
+		case strings.HasPrefix(query, "mutation"):
+			inputs = append(inputs, variables["input"].(map[string]interface{}))
+			return `{"updateLabel":{"label":{"name":"Bug","color":"ee0000","description":null}}}`
+		case variables["labelName"] == "bug":
+			return `{"repository":{"label":{"id":"L1"}}}`
+		default:
+			return `{"repository":{"label":null}}`
+		}
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil).(issues.LabelManager)
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+
+	name, color := "Bug", issues.RGB{R: 0xee}
+	l, err := s.EditLabel(ctx, repo, "bug", issues.LabelRequest{Name: &name, Color: &color})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (issues.Label{Name: "Bug", Color: color}); l != want {
+		t.Errorf("got label %+v, want %+v", l, want)
+	}
+	// The label is updated by its node ID, with its color as a hex string without "#".
+	want := []map[string]interface{}{{"id": "L1", "name": "Bug", "color": "ee0000"}}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("got mutation inputs %v, want %v", inputs, want)
+	}
+
+	// Labels that don't exist aren't mutated.
+	if _, err := s.EditLabel(ctx, repo, "docs", issues.LabelRequest{Name: &name}); !os.IsNotExist(err) {
+		t.Errorf("EditLabel of an unknown label: got error %v, want os.ErrNotExist", err)
+	}
+	if err := s.DeleteLabel(ctx, repo, "docs"); !os.IsNotExist(err) {
+		t.Errorf("DeleteLabel of an unknown label: got error %v, want os.ErrNotExist", err)
+	}
+	if len(inputs) != 1 {
+		t.Errorf("got %d mutations, want 1", len(inputs))
+	}
+}
@@ -0,0 +1,148 @@
+package githubapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"dmitri.shuralyov.com/route/github"
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/notifications"
+)
+
+// NewMultiUserService creates a GitHub-backed issues.Service that serves multiple users.
+// Each request is made on behalf of the user whose GitHub token tokens provides for
+// the request context. If tokens is nil, the token is taken from the context,
+// where it's put by WithToken. Requests without a token are unauthenticated.
+//
+// GitHub clients are created for each user as needed, and reused for their later requests.
+// They make HTTP requests via transport, or http.DefaultTransport if transport is nil.
+// It uses notifications service, if not nil. It must serve the user of the request context.
+//
+// If router is nil, github.DotCom router is used, which links to subjects on github.com.
+func NewMultiUserService(tokens TokenSource, transport http.RoundTripper, notifications notifications.ExternalService, router github.Router) issues.Service {
+	if tokens == nil {
+		tokens = contextTokens{}
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if router == nil {
+		router = github.DotCom{}
+	}
+	return service{
+		rtr:           router,
+		notifications: notifications,
+		users: &userClients{
+			tokens:    tokens,
+			transport: transport,
+			clients:   make(map[string]*githubv4.Client),
+		},
+	}
+}
+
+// TokenSource provides GitHub tokens of users.
+type TokenSource interface {
+	// Token returns the GitHub token of the user that ctx is for,
+	// or the empty string if the request is unauthenticated.
+	Token(ctx context.Context) (string, error)
+}
+
+// WithToken returns a copy of ctx that carries GitHub token.
+// It's used by services created by NewMultiUserService with a nil TokenSource.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey, token)
+}
+
+// contextTokens is a TokenSource that provides tokens put in context by WithToken.
+type contextTokens struct{}
+
+func (contextTokens) Token(ctx context.Context) (string, error) {
+	token, _ := ctx.Value(tokenContextKey).(string)
+	return token, nil
+}
+
+// tokenContextKey is a context key. The associated value is a GitHub token string.
+var tokenContextKey = &contextKey{"Token"}
+
+// contextKey is a value for use with context.WithValue. It's used as
+// a pointer so it fits in an interface{} without allocation.
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string { return "githubapi context value " + k.name }
+
+// maxUserClients is the maximum number of users whose clients are kept for reuse.
+// All clients are dropped when it's exceeded, which bounds memory use as tokens change.
+const maxUserClients = 1000
+
+// userClients creates and reuses GitHub clients for users, by their tokens.
+type userClients struct {
+	tokens    TokenSource
+	transport http.RoundTripper
+
+	mu      sync.Mutex
+	clients map[string]*githubv4.Client // Token -> client.
+}
+
+// get returns the client for the user that ctx is for.
+// If their token can't be obtained, the returned client fails all requests.
+func (uc *userClients) get(ctx context.Context) *githubv4.Client {
+	token, err := uc.tokens.Token(ctx)
+	if err != nil {
+		return newClient(errorTransport{err: err})
+	}
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if c, ok := uc.clients[token]; ok {
+		return c
+	}
+	if len(uc.clients) >= maxUserClients {
+		uc.clients = make(map[string]*githubv4.Client)
+	}
+	c := newClient(tokenTransport{token: token, base: uc.transport})
+	uc.clients[token] = c
+	return c
+}
+
+func newClient(transport http.RoundTripper) *githubv4.Client {
+	return githubv4.NewClient(&http.Client{Transport: transport})
+}
+
+// v4 returns the GitHub GraphQL API v4 client for the user that ctx is for.
+func (s service) v4(ctx context.Context) *githubv4.Client {
+	if s.users == nil {
+		return s.cl
+	}
+	return s.users.get(ctx)
+}
+
+// tokenTransport is an http.RoundTripper that authenticates requests with token,
+// unless it's empty.
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token == "" {
+		return t.base.RoundTrip(req)
+	}
+	req2 := req.Clone(req.Context()) // Per RoundTripper contract, don't modify req.
+	req2.Header.Set("Authorization", "bearer "+t.token)
+	return t.base.RoundTrip(req2)
+}
+
+// errorTransport is an http.RoundTripper that fails all requests with err.
+type errorTransport struct {
+	err error
+}
+
+func (t errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
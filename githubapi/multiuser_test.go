@@ -0,0 +1,71 @@
+package githubapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shurcooL/issues"
+)
+
+func TestMultiUserViewerCanUpdate(t *testing.T) {
+	// Only alice can update the issue.
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		var data string
+		switch {
+		case strings.Contains(string(body), "projectItems"):
+			data = `{"repository":{"issue":{"projectItems":{"nodes":[]}}}}`
+		default:
+			canUpdate := req.Header.Get("Authorization") == "bearer alice"
+			data = fmt.Sprintf(`{"repository":{"issue":{"number":1,"state":"OPEN","title":"Issue","author":null,"publishedAt":"2018-01-01T00:00:00Z","viewerCanUpdate":%t}}}`, canUpdate)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"data":` + data + `}`)),
+			Request:    req,
+		}, nil
+	})
+	s := NewMultiUserService(nil, transport, nil, nil)
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+
+	var wg sync.WaitGroup
+	for _, user := range []struct {
+		token         string
+		wantCanUpdate bool
+	}{
+		{"alice", true},
+		{"bob", false},
+	} {
+		user := user
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := WithToken(context.Background(), user.token)
+			for i := 0; i < 20; i++ {
+				issue, err := s.Get(ctx, repo, 1)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got := issue.Editable; got != user.wantCanUpdate {
+					t.Errorf("%s: got Editable %v, want %v", user.token, got, user.wantCanUpdate)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
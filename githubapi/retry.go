@@ -0,0 +1,337 @@
+package githubapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimited is the error returned when GitHub rate limits a request,
+// and it can't be retried in time. Since it's returned from an http.RoundTripper,
+// it reaches callers wrapped, so use errors.As to check for it.
+type RateLimited struct {
+	Reset     time.Time // Reset is when the rate limit is expected to be lifted.
+	Secondary bool      // Secondary reports whether it's a secondary rate limit.
+}
+
+func (e *RateLimited) Error() string {
+	kind := "rate limit"
+	if e.Secondary {
+		kind = "secondary rate limit"
+	}
+	return fmt.Sprintf("GitHub %s exceeded, resets at %v", kind, e.Reset.Format(time.RFC3339))
+}
+
+// RetryOptions configure RetryTransport.
+type RetryOptions struct {
+	// MaxRetries is the maximum number of times a request is retried.
+	// If zero, 3 is used.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry after a server error.
+	// It's doubled for every following retry, and jittered. If zero, 1 second is used.
+	MinBackoff time.Duration
+
+	// MaxWait is the longest time to wait for a rate limit to be lifted
+	// before retrying. If zero, 1 minute is used.
+	MaxWait time.Duration
+}
+
+// RetryTransport returns an http.RoundTripper that makes requests via base,
+// or http.DefaultTransport if base is nil, handling GitHub rate limits and server errors.
+// If opt is nil, default options are used.
+//
+// Requests that are rate limited, including GraphQL queries that fail with
+// a RATE_LIMITED error, are retried once the limit is lifted, if that's within
+// opt.MaxWait and before the deadline of the request context. Otherwise, they fail fast
+// with a *RateLimited error. Once GitHub reports no remaining rate limit for a user,
+// their requests fail fast or wait without being sent until the reported reset time.
+// GraphQL queries that select rateLimit { cost remaining resetAt } report their cost
+// as well. Once the remaining rate limit is less than that cost, a query like it would
+// be rejected, so following requests of the user fail fast or wait until resetAt
+// without being sent. The cost of other queries isn't known before they're sent,
+// so one that costs more than the remaining rate limit, even when it's not zero,
+// is sent, and it's retried or fails once GitHub responds with a RATE_LIMITED error.
+//
+// Idempotent requests (REST API v3 GET requests and GraphQL API v4 queries)
+// that fail with 502, 503 or 504 status are retried with jittered exponential backoff.
+//
+// It must come after authentication, so that it can tell users apart.
+// If used with Cache, it should be the base of the cache's transport.
+func RetryTransport(base http.RoundTripper, opt *RetryOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &retryTransport{
+		base:       base,
+		maxRetries: 3,
+		minBackoff: time.Second,
+		maxWait:    time.Minute,
+		resets:     make(map[string]time.Time),
+	}
+	if opt != nil {
+		if opt.MaxRetries != 0 {
+			t.maxRetries = opt.MaxRetries
+		}
+		if opt.MinBackoff != 0 {
+			t.minBackoff = opt.MinBackoff
+		}
+		if opt.MaxWait != 0 {
+			t.maxWait = opt.MaxWait
+		}
+	}
+	return t
+}
+
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	minBackoff time.Duration
+	maxWait    time.Duration
+
+	mu     sync.Mutex
+	resets map[string]time.Time // Authorization header -> time when exhausted rate limit resets.
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	graphQL := req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/graphql")
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead ||
+		(graphQL && !isGraphQLMutation(body))
+	user := req.Header.Get("Authorization")
+
+	for attempt := 0; ; attempt++ {
+		// Don't send requests that are known to be rate limited.
+		if reset, ok := t.exhausted(user); ok {
+			if err := t.waitReset(req.Context(), &RateLimited{Reset: reset}); err != nil {
+				return nil, err
+			}
+		}
+
+		r := req.Clone(req.Context()) // Per RoundTripper contract, don't modify req.
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		t.observe(user, resp)
+
+		if rl, err := rateLimited(resp, graphQL); err != nil {
+			return nil, err
+		} else if rl != nil {
+			resp.Body.Close()
+			if attempt == t.maxRetries {
+				return nil, rl
+			}
+			// Rate limited requests aren't processed by GitHub, so it's safe to retry any of them.
+			if err := t.waitReset(req.Context(), rl); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if graphQL {
+			if err := t.observeCost(user, resp); err != nil {
+				return nil, err
+			}
+		}
+
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if !idempotent || attempt == t.maxRetries {
+				return resp, nil
+			}
+			resp.Body.Close()
+			if err := sleep(req.Context(), jitter(t.minBackoff<<uint(attempt))); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// observe records the rate limit state of user reported in resp.
+func (t *retryTransport) observe(user string, resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	reset, ok := parseReset(resp.Header)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.resets[user] = reset
+	t.mu.Unlock()
+}
+
+// observeCost records the rate limit state of user reported in the rateLimit object
+// of GraphQL response resp, if the query selected it. If the remaining rate limit is
+// less than the cost of the query, a query like it would be rejected, so the rate limit
+// is recorded as exhausted until it resets. resp's body is replaced with an equivalent one.
+func (t *retryTransport) observeCost(user string, resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	body, err := peekBody(resp)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		Data struct {
+			RateLimit *struct {
+				Cost      int
+				Remaining int
+				ResetAt   time.Time
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &r); err != nil || r.Data.RateLimit == nil {
+		return nil
+	}
+	if rl := r.Data.RateLimit; rl.Remaining < rl.Cost {
+		t.mu.Lock()
+		t.resets[user] = rl.ResetAt
+		t.mu.Unlock()
+	}
+	return nil
+}
+
+// exhausted reports whether user is known to have exhausted their rate limit,
+// and returns when it resets.
+func (t *retryTransport) exhausted(user string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	reset, ok := t.resets[user]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !time.Now().Before(reset) {
+		delete(t.resets, user)
+		return time.Time{}, false
+	}
+	return reset, true
+}
+
+// waitReset waits until the rate limit rl is lifted, or returns rl if that
+// takes longer than t.maxWait or is past the deadline of ctx.
+func (t *retryTransport) waitReset(ctx context.Context, rl *RateLimited) error {
+	wait := time.Until(rl.Reset)
+	if wait > t.maxWait {
+		return rl
+	}
+	if deadline, ok := ctx.Deadline(); ok && rl.Reset.After(deadline) {
+		return rl
+	}
+	return sleep(ctx, wait)
+}
+
+// rateLimited returns a non-nil *RateLimited if resp reports that
+// the request, a GraphQL request if graphQL is true, was rate limited.
+// If resp's body needs to be read to tell, it's replaced with an equivalent one.
+func rateLimited(resp *http.Response, graphQL bool) (*RateLimited, error) {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if secs, err := strconv.Atoi(s); err == nil {
+				return &RateLimited{Reset: time.Now().Add(time.Duration(secs) * time.Second), Secondary: true}, nil
+			}
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, ok := parseReset(resp.Header); ok {
+				return &RateLimited{Reset: reset}, nil
+			}
+		}
+		body, err := peekBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit")) {
+			// GitHub recommends waiting at least a minute if there's no Retry-After header.
+			return &RateLimited{Reset: time.Now().Add(time.Minute), Secondary: true}, nil
+		}
+	case http.StatusOK:
+		// The remaining rate limit isn't necessarily zero, since it may be less than the cost of the query.
+		if !graphQL || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			return nil, nil
+		}
+		body, err := peekBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		var r struct {
+			Errors []struct{ Type string }
+		}
+		json.Unmarshal(body, &r)
+		for _, e := range r.Errors {
+			if e.Type == "RATE_LIMITED" {
+				reset, ok := parseReset(resp.Header)
+				if !ok {
+					reset = time.Now().Add(time.Minute)
+				}
+				return &RateLimited{Reset: reset}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// parseReset parses the X-RateLimit-Reset header.
+func parseReset(h http.Header) (time.Time, bool) {
+	secs, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// peekBody reads the body of resp, and replaces it with an equivalent one.
+func peekBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// jitter returns a random duration in [d/2, 3d/2).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	var requests int
+	statuses := func(codes ...int) roundTripFunc {
+		requests = 0
+		return func(req *http.Request) (*http.Response, error) {
+			code := codes[requests]
+			requests++
+			return &http.Response{StatusCode: code, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{}`)), Request: req}, nil
+		}
+	}
+	opt := &RetryOptions{MinBackoff: time.Millisecond}
+	graphQL := func(query string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"`+query+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	// Idempotent requests are retried after server errors.
+	resp, err := RetryTransport(statuses(502, 503, 200), opt).RoundTrip(graphQL("{viewer{login}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Errorf("got status %d after %d requests, want 200 after 3", resp.StatusCode, requests)
+	}
+
+	// Mutations aren't.
+	resp, err = RetryTransport(statuses(502, 200), opt).RoundTrip(graphQL("mutation{x}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadGateway || requests != 1 {
+		t.Errorf("got status %d after %d requests, want 502 after 1", resp.StatusCode, requests)
+	}
+}
+
+func TestRetryTransportRateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	var requests int
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Header: http.Header{
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+			},
+			Body:    io.NopCloser(strings.NewReader(`{"message":"API rate limit exceeded"}`)),
+			Request: req,
+		}, nil
+	})
+	rt := RetryTransport(upstream, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/repos/owner/repo/issues", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = rt.RoundTrip(req)
+		var rl *RateLimited
+		if !errors.As(err, &rl) || !rl.Reset.Equal(reset) || rl.Secondary {
+			t.Errorf("request %d: got error %v, want *RateLimited with reset %v", i, err, reset)
+		}
+	}
+	// The second request fails fast without being sent, since the rate limit is known to be exhausted.
+	if requests != 1 {
+		t.Errorf("got %d upstream requests, want 1", requests)
+	}
+}
+
+func TestRetryTransportGraphQLCost(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// The query costs more than the remaining rate limit.
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Type":          {"application/json; charset=utf-8"},
+				"X-Ratelimit-Remaining": {"10"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+			},
+			Body:    io.NopCloser(strings.NewReader(`{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`)),
+			Request: req,
+		}, nil
+	})
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"{viewer{login}}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = RetryTransport(upstream, nil).RoundTrip(req)
+	var rl *RateLimited
+	if !errors.As(err, &rl) || !rl.Reset.Equal(reset) {
+		t.Errorf("got error %v, want *RateLimited with reset %v", err, reset)
+	}
+}
+
+func TestRetryTransportGraphQLRateLimitObject(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	var requests int
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// Each query costs 5, and the second one leaves less than that.
+		remaining := 10 - 5*(requests+1)
+		requests++
+		body := fmt.Sprintf(`{"data":{"viewer":{"login":"gopher"},"rateLimit":{"cost":5,"remaining":%d,"resetAt":%q}}}`, remaining, reset.Format(time.RFC3339))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	rt := RetryTransport(upstream, nil)
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"{viewer{login},rateLimit{cost,remaining,resetAt}}"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := rt.RoundTrip(req)
+		if i < 2 {
+			if err != nil {
+				t.Fatalf("request %d: %v", i, err)
+			}
+			// The response body is still readable after the transport read it.
+			if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), `"gopher"`) {
+				t.Errorf("request %d: got body %q, want the query result", i, body)
+			}
+			continue
+		}
+		var rl *RateLimited
+		if !errors.As(err, &rl) || !rl.Reset.Equal(reset) {
+			t.Errorf("request %d: got error %v, want *RateLimited with reset %v", i, err, reset)
+		}
+	}
+	// The third query fails fast without being sent, since the remaining rate limit is less than its cost.
+	if requests != 2 {
+		t.Errorf("got %d upstream requests, want 2", requests)
+	}
+}
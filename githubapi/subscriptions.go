@@ -0,0 +1,119 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// Subscribe implements issues.Subscriber.
+// If id is 0, the authenticated user starts watching repo on GitHub.
+func (s service) Subscribe(ctx context.Context, rs issues.RepoSpec, id uint64) error {
+	return s.updateSubscription(ctx, rs, id, githubv4.SubscriptionStateSubscribed)
+}
+
+// Unsubscribe implements issues.Subscriber.
+// If id is 0, the authenticated user stops watching repo on GitHub,
+// but remains notified of conversations they participate in.
+func (s service) Unsubscribe(ctx context.Context, rs issues.RepoSpec, id uint64) error {
+	return s.updateSubscription(ctx, rs, id, githubv4.SubscriptionStateUnsubscribed)
+}
+
+// ListSubscribers implements issues.Subscriber.
+// GitHub API lists watchers of repositories, but not subscribers of issues,
+// so an error is returned if id is not 0.
+func (s service) ListSubscribers(ctx context.Context, rs issues.RepoSpec, id uint64) ([]users.User, error) {
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, err
+	}
+	if id != 0 {
+		return nil, errors.New("ListSubscribers: listing issue subscribers is not supported by GitHub API")
+	}
+	var q struct {
+		Repository struct {
+			Watchers struct {
+				Nodes    []*githubV4User
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"watchers(first:100,after:$watchersCursor)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+		"watchersCursor":  (*githubv4.String)(nil), // Start from beginning.
+	}
+	var us []users.User
+	for {
+		err := s.v4(ctx).Query(ctx, &q, variables)
+		if err != nil {
+			return us, err
+		}
+		for _, u := range q.Repository.Watchers.Nodes {
+			us = append(us, ghUser(u))
+		}
+		if !q.Repository.Watchers.PageInfo.HasNextPage {
+			break
+		}
+		variables["watchersCursor"] = githubv4.NewString(q.Repository.Watchers.PageInfo.EndCursor)
+	}
+	return us, nil
+}
+
+// updateSubscription sets the authenticated user's subscription state
+// for the specified issue, or for repo if id is 0.
+func (s service) updateSubscription(ctx context.Context, rs issues.RepoSpec, id uint64, state githubv4.SubscriptionState) error {
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return err
+	}
+	var subjectID githubv4.ID
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+	}
+	if id == 0 {
+		var q struct {
+			Repository struct {
+				ID githubv4.ID
+			} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+		}
+		err = s.v4(ctx).Query(ctx, &q, variables)
+		subjectID = q.Repository.ID
+	} else {
+		var q struct {
+			Repository struct {
+				Issue struct {
+					ID githubv4.ID
+				} `graphql:"issue(number:$issueNumber)"`
+			} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+		}
+		variables["issueNumber"] = githubv4.Int(id)
+		err = s.v4(ctx).Query(ctx, &q, variables)
+		subjectID = q.Repository.Issue.ID
+	}
+	if err != nil {
+		return err
+	}
+
+	var m struct {
+		UpdateSubscription struct {
+			Subscribable struct {
+				ViewerSubscription githubv4.SubscriptionState
+			}
+		} `graphql:"updateSubscription(input:$input)"`
+	}
+	input := githubv4.UpdateSubscriptionInput{
+		SubscribableID: subjectID,
+		State:          state,
+	}
+	return s.v4(ctx).Mutate(ctx, &m, input, nil)
+}
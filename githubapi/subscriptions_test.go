@@ -0,0 +1,82 @@
+package githubapi
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+func TestUpdateSubscription(t *testing.T) {
+	var inputs []map[string]interface{}
+	transport := graphQLTransport(func(query string, variables map[string]interface{}) string {
+		switch {
+		case strings.HasPrefix(query, "mutation"):
+			inputs = append(inputs, variables["input"].(map[string]interface{}))
+			return `{"updateSubscription":{"subscribable":{"viewerSubscription":"SUBSCRIBED"}}}`
+		case strings.Contains(query, "issue("):
+			return `{"repository":{"issue":{"id":"I1"}}}`
+		default:
+			return `{"repository":{"id":"R1"}}`
+		}
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil).(issues.Subscriber)
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+
+	if err := s.Subscribe(ctx, repo, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unsubscribe(ctx, repo, 0); err != nil {
+		t.Fatal(err)
+	}
+	// The issue is subscribed to by its node ID, and the repo by its node ID when id is 0.
+	want := []map[string]interface{}{
+		{"subscribableId": "I1", "state": "SUBSCRIBED"},
+		{"subscribableId": "R1", "state": "UNSUBSCRIBED"},
+	}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("got mutation inputs %v, want %v", inputs, want)
+	}
+}
+
+func TestListSubscribers(t *testing.T) {
+	var cursors []interface{}
+	transport := graphQLTransport(func(query string, variables map[string]interface{}) string {
+		cursors = append(cursors, variables["watchersCursor"])
+		if variables["watchersCursor"] == nil {
+			return `{"repository":{"watchers":{"nodes":[{"databaseId":1,"login":"gopher"}],"pageInfo":{"endCursor":"C1","hasNextPage":true}}}}`
+		}
+		return `{"repository":{"watchers":{"nodes":[{"databaseId":2,"login":"reviewer"}],"pageInfo":{"endCursor":"C2","hasNextPage":false}}}}`
+	})
+	s := NewService(githubv4.NewClient(&http.Client{Transport: transport}), nil, nil).(issues.Subscriber)
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "github.com/owner/repo"}
+
+	us, err := s.ListSubscribers(ctx, repo, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []users.UserSpec
+	for _, u := range us {
+		got = append(got, u.UserSpec)
+	}
+	want := []users.UserSpec{{ID: 1, Domain: "github.com"}, {ID: 2, Domain: "github.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got watchers %v, want %v", got, want)
+	}
+	// The second page starts after the end of the first.
+	if wantCursors := []interface{}{nil, "C1"}; !reflect.DeepEqual(cursors, wantCursors) {
+		t.Errorf("got cursors %v, want %v", cursors, wantCursors)
+	}
+
+	// Issue subscribers can't be listed.
+	if _, err := s.ListSubscribers(ctx, repo, 7); err == nil {
+		t.Error("ListSubscribers of an issue succeeded, want error")
+	}
+}
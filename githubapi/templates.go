@@ -0,0 +1,82 @@
+package githubapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/issues"
+)
+
+// ListTemplates implements issues.TemplateLister.
+// GitHub Markdown issue templates have no custom fields,
+// so none of the returned templates have required fields.
+func (s service) ListTemplates(ctx context.Context, rs issues.RepoSpec) ([]issues.Template, error) {
+	repo, err := ghRepoSpec(rs)
+	if err != nil {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, err
+	}
+	var q struct {
+		Repository struct {
+			IssueTemplates []struct {
+				Name   string
+				About  string
+				Title  string
+				Body   string
+				Labels struct {
+					Nodes []struct {
+						Name  string
+						Color string
+					}
+				} `graphql:"labels(first:100)"`
+			}
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+	variables := map[string]interface{}{
+		"repositoryOwner": githubv4.String(repo.Owner),
+		"repositoryName":  githubv4.String(repo.Repo),
+	}
+	err = s.v4(ctx).Query(ctx, &q, variables)
+	if err != nil {
+		return nil, err
+	}
+	var ts []issues.Template
+	for _, t := range q.Repository.IssueTemplates {
+		var labels []issues.Label
+		for _, l := range t.Labels.Nodes {
+			labels = append(labels, issues.Label{
+				Name:  l.Name,
+				Color: ghColor(l.Color),
+			})
+		}
+		ts = append(ts, issues.Template{
+			Name:        t.Name,
+			About:       t.About,
+			TitlePrefix: t.Title,
+			Body:        t.Body,
+			Labels:      labels,
+		})
+	}
+	return ts, nil
+}
+
+// CreateFromTemplate implements issues.TemplateLister.
+func (s service) CreateFromTemplate(ctx context.Context, rs issues.RepoSpec, name string, i issues.Issue) (issues.Issue, error) {
+	ts, err := s.ListTemplates(ctx, rs)
+	if err != nil {
+		return issues.Issue{}, err
+	}
+	for _, t := range ts {
+		if t.Name != name {
+			continue
+		}
+		i = t.Apply(i)
+		if err := t.ValidateIssue(i); err != nil {
+			return issues.Issue{}, err
+		}
+		return s.Create(ctx, rs, i)
+	}
+	// TODO: Map to 400 Bad Request HTTP error.
+	return issues.Issue{}, &issues.InvalidArgumentError{Field: "template", Reason: fmt.Sprintf("template %q doesn't exist", name)}
+}
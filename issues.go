@@ -70,20 +70,49 @@ type CopierFrom interface {
 	CopyFrom(ctx context.Context, src Service, repo RepoSpec) error
 }
 
+// Closer is an optional interface that allows closing and reopening issues
+// on behalf of a commit or a change, such as one whose message says "Fixes #12".
+type Closer interface {
+	// SetState sets the state of the specified issue id on behalf of the authenticated user.
+	// c is attached to the Closed event when closing; its Closer must be a Change, a Commit, an IssueRef, or nil.
+	// c is ignored when reopening. No events are created if the issue is already in state.
+	SetState(ctx context.Context, repo RepoSpec, id uint64, state State, c Close) (Issue, []Event, error)
+}
+
+// Subscriber is an optional interface that allows users to watch and unwatch issues,
+// and to see who is watching them.
+type Subscriber interface {
+	// Subscribe subscribes the authenticated user to notifications about the specified issue id.
+	// If id is 0, the user is subscribed to all new issues in repo.
+	Subscribe(ctx context.Context, repo RepoSpec, id uint64) error
+	// Unsubscribe unsubscribes the authenticated user from notifications about the specified issue id.
+	// If id is 0, the user is unsubscribed from new issues in repo, but not from existing ones.
+	Unsubscribe(ctx context.Context, repo RepoSpec, id uint64) error
+	// ListSubscribers lists users subscribed to the specified issue id, or to repo if id is 0.
+	ListSubscribers(ctx context.Context, repo RepoSpec, id uint64) ([]users.User, error)
+}
+
 // Issue represents an issue on a repository.
 type Issue struct {
 	ID     uint64
 	State  State
 	Title  string
 	Labels []Label
+	Fields []Field // Fields are values of custom fields that are set. See FieldLister.
+
+	// Assignees and Milestone are only supported by some services.
+	Assignees []users.User
+	Milestone *Milestone // Milestone is nil if the issue isn't in a milestone.
+
 	Comment
 	Replies int // Number of replies to this issue (not counting the mandatory issue description comment).
 }
 
 // Label represents a label.
 type Label struct {
-	Name  string
-	Color RGB
+	Name        string
+	Color       RGB
+	Description string // Description is optional. It may only be populated by LabelManager.
 }
 
 // TODO: Dedup.
@@ -135,9 +164,12 @@ type Edited struct {
 // IssueRequest is a request to edit an issue.
 // To edit the body, use EditComment with comment ID 0.
 type IssueRequest struct {
-	State *State
-	Title *string
-	// TODO: Labels *[]Label
+	State     *State
+	Title     *string
+	Labels    *[]Label          // If not nil, set labels to these. Only label names are used.
+	Assignees *[]users.UserSpec // If not nil, set assignees to these. Only supported by some services.
+	Milestone *Milestone        // If not nil, set the milestone, or clear it if Name is empty. Only supported by some services.
+	Fields    []Field           // If not empty, set values of these custom fields. A nil Value unsets a field.
 }
 
 // CommentRequest is a request to edit a comment.
@@ -162,7 +194,7 @@ func (i Issue) Validate() error {
 	if strings.TrimSpace(i.Title) == "" {
 		return fmt.Errorf("title can't be blank or all whitespace")
 	}
-	return nil
+	return validateFieldNames(i.Fields)
 }
 
 // Validate returns non-nil error if the issue request is invalid.
@@ -179,7 +211,14 @@ func (ir IssueRequest) Validate() error {
 			return fmt.Errorf("title can't be blank or all whitespace")
 		}
 	}
-	return nil
+	if ir.Labels != nil {
+		for _, l := range *ir.Labels {
+			if err := l.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return validateFieldNames(ir.Fields)
 }
 
 // Validate returns non-nil error if the comment is invalid.
@@ -0,0 +1,48 @@
+package issues
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// LabelManager is an optional interface that manages the catalog of labels
+// available in a repo.
+//
+// Renaming or recoloring a label in the catalog updates it on all issues that have it,
+// and deleting a label from the catalog removes it from all issues.
+type LabelManager interface {
+	// ListLabels lists labels in the catalog of the specified repo, sorted by name.
+	ListLabels(ctx context.Context, repo RepoSpec) ([]Label, error)
+	// CreateLabel adds label l to the catalog of the specified repo.
+	CreateLabel(ctx context.Context, repo RepoSpec, l Label) (Label, error)
+	// EditLabel edits the label with the specified name in the catalog of the specified repo.
+	EditLabel(ctx context.Context, repo RepoSpec, name string, lr LabelRequest) (Label, error)
+	// DeleteLabel deletes the label with the specified name from the catalog of the specified repo.
+	DeleteLabel(ctx context.Context, repo RepoSpec, name string) error
+}
+
+// LabelRequest is a request to edit a label.
+type LabelRequest struct {
+	Name        *string // If not nil, rename the label.
+	Color       *RGB    // If not nil, set the color.
+	Description *string // If not nil, set the description.
+}
+
+// Validate returns non-nil error if the label is invalid.
+func (l Label) Validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("label name can't be blank or all whitespace")
+	}
+	return nil
+}
+
+// Validate returns non-nil error if the request is invalid.
+func (lr LabelRequest) Validate() error {
+	if lr.Name != nil {
+		if strings.TrimSpace(*lr.Name) == "" {
+			return errors.New("label name can't be blank or all whitespace")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package maintner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dmitri.shuralyov.com/state"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/issues/closing"
+	"golang.org/x/build/maintner"
+)
+
+// closeWindow is how close in time a change must have been merged to
+// an issue being closed in order to be considered its closer, when
+// that can't be determined by the commit that closed the issue.
+const closeWindow = 5 * time.Minute
+
+// closer returns the closer of issue i in repo for closed event e:
+// an issues.Change, an issues.Commit, or nil if it's not known.
+// s.c must be locked for reading.
+func (s service) closer(repo *maintner.GitHubRepo, i *maintner.GitHubIssue, e *maintner.GitHubIssueEvent) interface{} {
+	if s.opt.Gerrit {
+		if c, ok := s.gerritCloser(repo, i, e); ok {
+			return c
+		}
+	}
+	if s.opt.PullRequests {
+		if c, ok := s.pullRequestCloser(repo, i, e); ok {
+			return c
+		}
+	}
+	if e.CommitID != "" {
+		return issues.Commit{
+			SHA:     e.CommitID,
+			HTMLURL: fmt.Sprintf("https://github.com/%s/commit/%s", repo.ID(), e.CommitID),
+		}
+	}
+	return nil
+}
+
+// gerritCloser finds a merged Gerrit CL that refers to issue i in repo
+// with a closing keyword, like "Fixes #N", and that either is the commit
+// that closed the issue, or was merged within closeWindow of closed event e.
+func (s service) gerritCloser(repo *maintner.GitHubRepo, i *maintner.GitHubIssue, e *maintner.GitHubIssueEvent) (issues.Change, bool) {
+	rs, want := closingRef(repo, i)
+	var (
+		best     *maintner.GerritCL
+		bestDiff = closeWindow + 1
+	)
+	for _, cl := range s.gerritCLs(repo, i) {
+		if cl.Status != "merged" || cl.Commit == nil {
+			continue
+		}
+		if e.CommitID != "" && cl.Commit.Hash.String() == e.CommitID {
+			best = cl
+			break
+		}
+		mergedAt, ok := gerritMergedAt(cl)
+		if !ok {
+			continue
+		}
+		diff := absDuration(e.Created.Sub(mergedAt))
+		if diff >= bestDiff || !closes(closing.Parse(rs, cl.Commit.Msg), want) {
+			continue
+		}
+		best, bestDiff = cl, diff
+	}
+	if best == nil {
+		return issues.Change{}, false
+	}
+	return gerritChange(best), true
+}
+
+// pullRequestIndexTTL is how long an index of merged pull requests by the issues
+// they close is used for, before it's rebuilt to include corpus updates.
+const pullRequestIndexTTL = time.Minute
+
+// pullRequestIndex is an index of merged pull requests in a corpus by the issues
+// they refer to with a closing keyword. Building it requires walking all pull
+// requests, so it's built once and reused for pullRequestIndexTTL, rather than
+// walking all pull requests for each closed event.
+type pullRequestIndex struct {
+	mu    sync.Mutex
+	built time.Time                           // Built is when prs was built.
+	prs   map[closing.Ref][]mergedPullRequest // Sorted by merge time.
+}
+
+// mergedPullRequest is a merged pull request and the event of it being merged.
+type mergedPullRequest struct {
+	Repo   *maintner.GitHubRepo
+	PR     *maintner.GitHubIssue
+	Merged *maintner.GitHubIssueEvent
+}
+
+// mergedPullRequests returns merged pull requests in the corpus that refer to
+// issue i in repo with a closing keyword, ordered by merge time.
+// s.c must be locked for reading.
+func (s service) mergedPullRequests(repo *maintner.GitHubRepo, i *maintner.GitHubIssue) []mergedPullRequest {
+	x := s.pullRequests
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.prs == nil || time.Since(x.built) > pullRequestIndexTTL {
+		x.prs = make(map[closing.Ref][]mergedPullRequest)
+		s.c.GitHub().ForeachRepo(func(prRepo *maintner.GitHubRepo) error {
+			rs := closingRepo(prRepo)
+			return prRepo.ForeachIssue(func(pr *maintner.GitHubIssue) error {
+				if pr.NotExist || !pr.PullRequest || !pr.Closed {
+					return nil
+				}
+				merged, ok := pullRequestMerged(pr)
+				if !ok {
+					// Closed without being merged, so it didn't close any issues.
+					return nil
+				}
+				for _, ref := range closing.Parse(rs, pr.Body) {
+					x.prs[ref] = append(x.prs[ref], mergedPullRequest{Repo: prRepo, PR: pr, Merged: merged})
+				}
+				return nil
+			})
+		})
+		for _, prs := range x.prs {
+			sort.SliceStable(prs, func(i, j int) bool { return prs[i].Merged.Created.Before(prs[j].Merged.Created) })
+		}
+		x.built = time.Now()
+	}
+	_, want := closingRef(repo, i)
+	return x.prs[want]
+}
+
+// pullRequestCloser finds a merged pull request that refers to issue i in repo
+// with a closing keyword, and that either merged the commit that closed the issue,
+// or was merged within closeWindow of closed event e.
+// s.c must be locked for reading.
+func (s service) pullRequestCloser(repo *maintner.GitHubRepo, i *maintner.GitHubIssue, e *maintner.GitHubIssueEvent) (issues.Change, bool) {
+	var (
+		best     *mergedPullRequest
+		bestDiff = closeWindow + 1
+	)
+	prs := s.mergedPullRequests(repo, i)
+	for j := range prs {
+		pr := &prs[j]
+		if e.CommitID != "" && pr.Merged.CommitID == e.CommitID {
+			best = pr
+			break
+		}
+		if diff := absDuration(e.Created.Sub(pr.Merged.Created)); diff < bestDiff {
+			best, bestDiff = pr, diff
+		}
+	}
+	if best == nil {
+		return issues.Change{}, false
+	}
+	return issues.Change{
+		State:   state.ChangeMerged,
+		Title:   best.PR.Title,
+		HTMLURL: fmt.Sprintf("https://github.com/%s/pull/%d", best.Repo.ID(), best.PR.Number),
+	}, true
+}
+
+// errStop stops iteration early.
+var errStop = errors.New("stop")
+
+// pullRequestMerged returns the event of pull request pr being merged,
+// and reports whether it was found.
+func pullRequestMerged(pr *maintner.GitHubIssue) (*maintner.GitHubIssueEvent, bool) {
+	var merged *maintner.GitHubIssueEvent
+	pr.ForeachEvent(func(e *maintner.GitHubIssueEvent) error {
+		if e.Type == "merged" {
+			merged = e
+			return errStop
+		}
+		return nil
+	})
+	return merged, merged != nil
+}
+
+// closingRef returns the repo to parse closing references made from repo with,
+// and the reference to issue i that closes it.
+// References are resolved on github.com, so that ones to other repos,
+// like "Fixes golang/go#N", work.
+func closingRef(repo *maintner.GitHubRepo, i *maintner.GitHubIssue) (issues.RepoSpec, closing.Ref) {
+	rs := closingRepo(repo)
+	return rs, closing.Ref{Repo: rs, ID: uint64(i.Number)}
+}
+
+// closingRepo returns the repo to parse closing references made from repo with.
+func closingRepo(repo *maintner.GitHubRepo) issues.RepoSpec {
+	return issues.RepoSpec{URI: "github.com/" + repo.ID().String()}
+}
+
+func closes(refs []closing.Ref, want closing.Ref) bool {
+	for _, r := range refs {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
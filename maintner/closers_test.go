@@ -0,0 +1,139 @@
+package maintner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"dmitri.shuralyov.com/state"
+	"github.com/shurcooL/issues"
+	"golang.org/x/build/maintner/maintpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCloser(t *testing.T) {
+	corpus := newCorpus(t,
+		// Issue 1 is closed by merged pull request 2. Pull request 3
+		// was closed closer in time, but without being merged.
+		githubIssue(1, "Issue 1", false, &maintpb.GithubIssueEvent{Id: 3001, EventType: "closed", ActorId: gopher.Id, Created: at(10)}),
+		githubPullRequest(2, "Fix issue 1", "Fixes #1.", at(8), &maintpb.GithubIssueEvent{Id: 3002, EventType: "merged", ActorId: gopher.Id, Created: at(8), Commit: &maintpb.GithubCommit{CommitId: "abc"}}),
+		githubPullRequest(3, "Also fix issue 1", "Fixes #1.", at(10)),
+
+		// Issue 4 is closed by CL 101, which refers to it with a closing keyword.
+		// CL 100 was merged closer in time, but only refers to the issue.
+		githubIssue(4, "Issue 4", false, &maintpb.GithubIssueEvent{Id: 3004, EventType: "closed", ActorId: gopher.Id, Created: at(20)}),
+		gerritCL(100, "cmd/go: update\n\nUpdates #4.\n", at(15), at(20)),
+		gerritCL(101, "cmd/go: fix\n\nFixes #4.\n", at(15), at(18)),
+
+		// Issue 5 is closed by the commit of CL 102.
+		githubIssue(5, "Issue 5", false, &maintpb.GithubIssueEvent{Id: 3005, EventType: "closed", ActorId: gopher.Id, Created: at(30), Commit: &maintpb.GithubCommit{CommitId: gerritCommit(102)}}),
+		gerritCL(102, "cmd/go: fix again\n\nFixes golang/go#5.\n", at(0), at(1)),
+
+		// Issue 6 is closed by a commit that's not a known CL or pull request.
+		githubIssue(6, "Issue 6", false, &maintpb.GithubIssueEvent{Id: 3006, EventType: "closed", ActorId: gopher.Id, Created: at(40), Commit: &maintpb.GithubCommit{CommitId: "def"}}),
+
+		// Issue 7 is closed by merged pull request 8 in another repo.
+		githubIssue(7, "Issue 7", false, &maintpb.GithubIssueEvent{Id: 3007, EventType: "closed", ActorId: gopher.Id, Created: at(50)}),
+		inRepo("tools", githubPullRequest(8, "Fix issue 7", "Fixes golang/go#7.", at(50), &maintpb.GithubIssueEvent{Id: 3008, EventType: "merged", ActorId: gopher.Id, Created: at(50)})),
+	)
+	s := NewService(corpus, &Options{PullRequests: true, Gerrit: true})
+	repo := issues.RepoSpec{URI: "golang/go"}
+
+	for _, tc := range []struct {
+		id   uint64
+		want interface{}
+	}{
+		{1, issues.Change{State: state.ChangeMerged, Title: "Fix issue 1", HTMLURL: "https://github.com/golang/go/pull/2"}},
+		{4, issues.Change{State: state.ChangeMerged, Title: "cmd/go: fix", HTMLURL: "https://go-review.googlesource.com/c/go/+/101"}},
+		{5, issues.Change{State: state.ChangeMerged, Title: "cmd/go: fix again", HTMLURL: "https://go-review.googlesource.com/c/go/+/102"}},
+		{6, issues.Commit{SHA: "def", HTMLURL: "https://github.com/golang/go/commit/def"}},
+		{7, issues.Change{State: state.ChangeMerged, Title: "Fix issue 7", HTMLURL: "https://github.com/golang/tools/pull/8"}},
+	} {
+		es, err := s.ListEvents(context.Background(), repo, tc.id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got interface{}
+		for _, e := range es {
+			if e.Type == issues.Closed {
+				got = e.Close.Closer
+			}
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("issue %d: got closer %+v, want %+v", tc.id, got, tc.want)
+		}
+	}
+}
+
+// githubIssue returns a mutation that creates issue number in golang/go, with events.
+func githubIssue(number int32, title string, open bool, events ...*maintpb.GithubIssueEvent) *maintpb.Mutation {
+	return &maintpb.Mutation{GithubIssue: &maintpb.GithubIssueMutation{
+		Owner:   "golang",
+		Repo:    "go",
+		Number:  number,
+		Id:      1000 + int64(number),
+		User:    gopher,
+		Created: at(0),
+		Updated: at(0),
+		Title:   title,
+		Closed:  &maintpb.BoolChange{Val: !open},
+		Event:   events,
+	}}
+}
+
+// githubPullRequest returns a mutation that creates pull request number in golang/go,
+// closed at closedAt, with events.
+func githubPullRequest(number int32, title, body string, closedAt *timestamppb.Timestamp, events ...*maintpb.GithubIssueEvent) *maintpb.Mutation {
+	m := githubIssue(number, title, false, events...)
+	m.GithubIssue.PullRequest = true
+	m.GithubIssue.Body = body
+	m.GithubIssue.ClosedAt = closedAt
+	return m
+}
+
+// inRepo moves the issue created by mutation m to repo in the golang org.
+func inRepo(repo string, m *maintpb.Mutation) *maintpb.Mutation {
+	m.GithubIssue.Repo = repo
+	return m
+}
+
+// gerritCL returns a mutation that creates CL number in the go.googlesource.com/go
+// Gerrit project with commit message msg, created at created, and merged at merged,
+// unless it's nil.
+func gerritCL(number int, msg string, created, merged *timestamppb.Timestamp) *maintpb.Mutation {
+	const tree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	raw := func(parent, author string, t *timestamppb.Timestamp, msg string) []byte {
+		hdr := "tree " + tree + "\n"
+		if parent != "" {
+			hdr += "parent " + parent + "\n"
+		}
+		hdr += fmt.Sprintf("author %s %d +0000\ncommitter %[1]s %[2]d +0000\n", author, t.AsTime().Unix())
+		return []byte(hdr + "\n" + msg)
+	}
+	commit := gerritCommit(number)
+	meta := fmt.Sprintf("%040x", number*10+1)
+	commits := []*maintpb.GitCommit{
+		{Sha1: commit, Raw: raw("", "Gopher <gopher@golang.org>", created, msg)},
+		{Sha1: meta, Raw: raw("", "Gopher <1@gerrit>", created, "Create change\n\nPatch-set: 1\n")},
+	}
+	if merged != nil {
+		parent := meta
+		meta = fmt.Sprintf("%040x", number*10+2)
+		commits = append(commits, &maintpb.GitCommit{Sha1: meta, Raw: raw(parent, "Gerrit <2@gerrit>", merged, "Update patch set 1\n\nChange has been successfully merged.\n\nPatch-set: 1\nStatus: merged\n")})
+	}
+	refs := fmt.Sprintf("refs/changes/%02d/%d/", number%100, number)
+	return &maintpb.Mutation{Gerrit: &maintpb.GerritMutation{
+		Project: "go.googlesource.com/go",
+		Commits: commits,
+		Refs: []*maintpb.GitRef{
+			{Ref: refs + "1", Sha1: commit},
+			{Ref: refs + "meta", Sha1: meta},
+		},
+	}}
+}
+
+// gerritCommit returns the commit hash of the patch set of CL number created by gerritCL.
+func gerritCommit(number int) string {
+	return fmt.Sprintf("%040x", number*10)
+}
@@ -0,0 +1,68 @@
+package maintner
+
+import (
+	"context"
+
+	"github.com/shurcooL/issues"
+)
+
+// LabelColorSource is a source of label colors.
+//
+// maintner.Corpus knows labels of GitHub issues by name and ID, but not their colors.
+type LabelColorSource interface {
+	// LabelColors returns colors of labels in the specified repo by label name.
+	// Labels missing from the result use a default color.
+	LabelColors(ctx context.Context, repo issues.RepoSpec) (map[string]issues.RGB, error)
+}
+
+// StaticLabelColors is a LabelColorSource that maps label names
+// to the same colors in all repos.
+type StaticLabelColors map[string]issues.RGB
+
+// LabelColors implements LabelColorSource.
+func (m StaticLabelColors) LabelColors(context.Context, issues.RepoSpec) (map[string]issues.RGB, error) {
+	return m, nil
+}
+
+// CatalogLabelColors returns a LabelColorSource that uses
+// colors from the label catalog of lm, such as an fs service.
+func CatalogLabelColors(lm issues.LabelManager) LabelColorSource {
+	return catalogLabelColors{lm: lm}
+}
+
+type catalogLabelColors struct {
+	lm issues.LabelManager
+}
+
+func (c catalogLabelColors) LabelColors(ctx context.Context, repo issues.RepoSpec) (map[string]issues.RGB, error) {
+	ls, err := c.lm.ListLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	colors := make(map[string]issues.RGB, len(ls))
+	for _, l := range ls {
+		colors[l.Name] = l.Color
+	}
+	return colors, nil
+}
+
+// defaultLabelColor is the color of labels whose color isn't known.
+var defaultLabelColor = issues.RGB{R: 0xed, G: 0xed, B: 0xed} // Light gray.
+
+// labelColors returns colors of labels in repo from Options.LabelColors, if any.
+func (s service) labelColors(ctx context.Context, repo issues.RepoSpec) (map[string]issues.RGB, error) {
+	if s.opt.LabelColors == nil {
+		return nil, nil
+	}
+	return s.opt.LabelColors.LabelColors(ctx, repo)
+}
+
+// labelColor returns the color of the label with the specified name,
+// or defaultLabelColor if it's not in colors.
+func labelColor(colors map[string]issues.RGB, name string) issues.RGB {
+	c, ok := colors[name]
+	if !ok {
+		return defaultLabelColor
+	}
+	return c
+}
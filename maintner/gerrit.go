@@ -0,0 +1,138 @@
+package maintner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dmitri.shuralyov.com/state"
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+	"golang.org/x/build/maintner"
+)
+
+// gerritIndexTTL is how long an index of Gerrit CLs by the issues they
+// reference is used for, before it's rebuilt to include corpus updates.
+const gerritIndexTTL = time.Minute
+
+// gerritIndex is an index of Gerrit CLs in a corpus by the issues they reference.
+// Building it requires walking all CLs, so it's built once and reused
+// for gerritIndexTTL, rather than walking all CLs for each issue.
+type gerritIndex struct {
+	mu    sync.Mutex
+	built time.Time                                        // Built is when cls was built.
+	cls   map[maintner.GitHubIssueRef][]*maintner.GerritCL // Sorted by creation time.
+}
+
+// gerritCLs returns Gerrit CLs in the corpus that reference issue i in repo,
+// ordered by creation time. Private CLs are skipped.
+// s.c must be locked for reading.
+func (s service) gerritCLs(repo *maintner.GitHubRepo, i *maintner.GitHubIssue) []*maintner.GerritCL {
+	x := s.gerrit
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.cls == nil || time.Since(x.built) > gerritIndexTTL {
+		x.cls = make(map[maintner.GitHubIssueRef][]*maintner.GerritCL)
+		s.c.Gerrit().ForeachProjectUnsorted(func(gp *maintner.GerritProject) error {
+			return gp.ForeachCLUnsorted(func(cl *maintner.GerritCL) error {
+				if cl.Private {
+					return nil
+				}
+				for _, ref := range cl.GitHubIssueRefs {
+					x.cls[ref] = append(x.cls[ref], cl)
+				}
+				return nil
+			})
+		})
+		for _, cls := range x.cls {
+			sort.Slice(cls, func(i, j int) bool { return cls[i].Created.Before(cls[j].Created) })
+		}
+		x.built = time.Now()
+	}
+	return x.cls[maintner.GitHubIssueRef{Repo: repo, Number: i.Number}]
+}
+
+// gerritEvents returns a CrossReferenced event for each Gerrit CL
+// that references issue i in repo.
+// s.c must be locked for reading.
+func (s service) gerritEvents(repo *maintner.GitHubRepo, i *maintner.GitHubIssue) []issues.Event {
+	var es []issues.Event
+	for _, cl := range s.gerritCLs(repo, i) {
+		es = append(es, issues.Event{
+			ID:        gerritEventID(cl),
+			Actor:     gerritUser(cl.Owner()),
+			CreatedAt: cl.Created,
+			Type:      issues.CrossReferenced,
+			CrossReference: &issues.CrossReference{
+				Source: gerritChange(cl),
+			},
+		})
+	}
+	return es
+}
+
+// gerritChange converts a Gerrit CL into an issues.Change.
+func gerritChange(cl *maintner.GerritCL) issues.Change {
+	return issues.Change{
+		State:   gerritState(cl.Status),
+		Title:   cl.Subject(),
+		HTMLURL: gerritCLURL(cl),
+	}
+}
+
+// gerritState converts a Gerrit CL status into a state.Change.
+func gerritState(status string) state.Change {
+	switch status {
+	case "merged":
+		return state.ChangeMerged
+	case "abandoned":
+		return state.ChangeClosed
+	default:
+		return state.ChangeOpen
+	}
+}
+
+// gerritUser converts a Gerrit CL owner into a users.User.
+// Gerrit users have no GitHub identity, so only their name and email are known.
+func gerritUser(p *maintner.GitPerson) users.User {
+	if p == nil {
+		return users.User{Login: "Someone"}
+	}
+	return users.User{
+		Login: p.Name(),
+		Name:  p.Name(),
+		Email: p.Email(),
+	}
+}
+
+// gerritEventID returns an event ID for a CL. It's derived from the
+// CL's server, project and number, since Gerrit CLs have no event IDs.
+func gerritEventID(cl *maintner.GerritCL) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d", cl.Project.ServerSlashProject(), cl.Number)
+	return h.Sum64()
+}
+
+// gerritMergedAt returns the time CL cl was merged,
+// and reports whether it was found.
+func gerritMergedAt(cl *maintner.GerritCL) (time.Time, bool) {
+	for _, m := range cl.Metas {
+		if strings.Contains("\n"+m.Footer()+"\n", "\nStatus: merged\n") {
+			return m.Commit.CommitTime, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// gerritCLURL returns the URL of CL cl on its Gerrit review server.
+// For example, "https://go-review.googlesource.com/c/net/+/123".
+func gerritCLURL(cl *maintner.GerritCL) string {
+	server := cl.Project.Server()
+	if i := strings.Index(server, "."); i != -1 && strings.HasSuffix(server, ".googlesource.com") {
+		server = server[:i] + "-review" + server[i:]
+	}
+	return fmt.Sprintf("https://%s/c/%s/+/%d", server, cl.Project.Project(), cl.Number)
+}
@@ -0,0 +1,56 @@
+package maintner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"dmitri.shuralyov.com/state"
+	"github.com/shurcooL/issues"
+	"golang.org/x/build/maintner/maintpb"
+)
+
+func TestGerritEvents(t *testing.T) {
+	corpus := newCorpus(t,
+		githubIssue(7, "Issue 7", true, &maintpb.GithubIssueEvent{Id: 3007, EventType: "labeled", ActorId: gopher.Id, Created: at(5), Label: &maintpb.GithubLabel{Name: "NeedsFix"}}),
+		githubIssue(8, "Issue 8", true),
+		gerritCL(201, "net/http: fix\n\nFixes #7.\n", at(10), at(12)),
+		gerritCL(200, "net/http: prepare\n\nUpdates #7.\n", at(2), nil),
+		gerritCL(202, "net/http: unrelated\n\nFixes #8.\n", at(3), nil),
+	)
+	repo := issues.RepoSpec{URI: "golang/go"}
+
+	// Without the Gerrit option, CLs aren't used.
+	es, err := NewService(corpus, nil).ListEvents(context.Background(), repo, 7, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 {
+		t.Errorf("got %d events without the Gerrit option, want 1", len(es))
+	}
+
+	s := NewService(corpus, &Options{Gerrit: true})
+	for i := 0; i < 2; i++ { // The second time, the index of CLs is reused.
+		es, err := s.ListEvents(context.Background(), repo, 7, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []interface{}
+		for _, e := range es {
+			switch e.Type {
+			case issues.CrossReferenced:
+				got = append(got, e.CrossReference.Source)
+			default:
+				got = append(got, e.Type)
+			}
+		}
+		want := []interface{}{
+			issues.Change{State: state.ChangeOpen, Title: "net/http: prepare", HTMLURL: "https://go-review.googlesource.com/c/go/+/200"},
+			issues.Labeled,
+			issues.Change{State: state.ChangeMerged, Title: "net/http: fix", HTMLURL: "https://go-review.googlesource.com/c/go/+/201"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got events %+v, want %+v", got, want)
+		}
+	}
+}
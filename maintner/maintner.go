@@ -1,5 +1,6 @@
-// Package maintner implements a read-only issues.Service using
-// a x/build/maintner corpus.
+// Package maintner implements an issues.Service using
+// a x/build/maintner corpus. It's read-only unless a mutator
+// is provided via Options.Mutator.
 package maintner
 
 import (
@@ -15,23 +16,89 @@ import (
 )
 
 // NewService creates an issues.Service backed with the given corpus.
-func NewService(corpus *maintner.Corpus) issues.Service {
-	return service{
-		c: corpus,
+//
+// If opt is nil, default options are used.
+func NewService(corpus *maintner.Corpus, opt *Options) issues.Service {
+	if opt == nil {
+		opt = new(Options)
 	}
+	s := service{
+		c:   corpus,
+		opt: *opt,
+	}
+	if opt.PullRequests {
+		s.pullRequests = new(pullRequestIndex)
+	}
+	if opt.Gerrit {
+		s.gerrit = new(gerritIndex)
+	}
+	if opt.Mutator != nil {
+		s.ov = &overlay{issues: make(map[overlayKey]*overlayIssue)}
+	}
+	return s
+}
+
+// Options are optional behaviors of the service.
+type Options struct {
+	// LabelColors is the source of label colors, since maintner.Corpus
+	// doesn't track them. If nil, or if it doesn't know a label,
+	// a default light gray is used.
+	LabelColors LabelColorSource
+
+	// PullRequests enables resolving closers of closed issues from merged pull
+	// requests in the corpus whose description refers to the issue with a closing
+	// keyword, like "Fixes #N". Merged pull requests are indexed by the issues
+	// they close, and the index is rebuilt at most once a minute, so new closers
+	// take up to a minute to show up.
+	PullRequests bool
+
+	// Gerrit enables using Gerrit CLs in the corpus. CLs that reference an issue
+	// appear in its timeline as CrossReferenced events with an issues.Change source,
+	// and merged ones that refer to the issue with a closing keyword are resolved
+	// as closers of the issue. CLs are indexed by the issues they reference,
+	// and the index is rebuilt at most once a minute, so new references take
+	// up to a minute to show up.
+	Gerrit bool
+
+	// Mutator, if non-nil, makes the service writable. Create, CreateComment,
+	// Edit and EditComment are delegated to it, and written data is overlaid
+	// on reads from the corpus until the corpus catches up with it.
+	Mutator issues.Service
+
+	// MutatorRepo maps a repo of the service to the corresponding repo of Mutator.
+	// For example, "owner/repo" to "github.com/owner/repo" for a githubapi mutator.
+	// If nil, repos are passed to Mutator as is.
+	MutatorRepo func(issues.RepoSpec) issues.RepoSpec
 }
 
 type service struct {
-	c *maintner.Corpus
+	c            *maintner.Corpus
+	opt          Options
+	pullRequests *pullRequestIndex // Non-nil if opt.PullRequests is true.
+	gerrit       *gerritIndex      // Non-nil if opt.Gerrit is true.
+	ov           *overlay          // Non-nil if opt.Mutator is non-nil.
 }
 
-func (s service) List(_ context.Context, rs issues.RepoSpec, opt issues.IssueListOptions) ([]issues.Issue, error) {
+func (s service) List(ctx context.Context, rs issues.RepoSpec, opt issues.IssueListOptions) ([]issues.Issue, error) {
 	// TODO: Pagination.
 
+	if len(opt.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, &issues.InvalidArgumentError{Field: "Fields", Reason: "filtering by custom fields is not supported"}
+	}
+	if opt.Sort != "" {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, &issues.InvalidArgumentError{Field: "Sort", Reason: "sorting by custom fields is not supported"}
+	}
+
 	repoID, err := ghRepoID(rs)
 	if err != nil {
 		return nil, err
 	}
+	colors, err := s.labelColors(ctx, rs)
+	if err != nil {
+		return nil, err
+	}
 	s.c.RLock()
 	defer s.c.RUnlock()
 	repo := s.c.GitHub().Repo(repoID.Owner, repoID.Repo)
@@ -39,28 +106,14 @@ func (s service) List(_ context.Context, rs issues.RepoSpec, opt issues.IssueLis
 		return nil, fmt.Errorf("repo %v not found", rs)
 	}
 
+	ovs := s.repoOverlays(rs, repo)
+
 	var is []issues.Issue
 	err = repo.ForeachIssue(func(i *maintner.GitHubIssue) error {
 		if i.NotExist || i.PullRequest {
 			return nil
 		}
 
-		state := ghState(i)
-		switch {
-		case opt.State == issues.StateFilter(issues.OpenState) && state != issues.OpenState:
-			return nil
-		case opt.State == issues.StateFilter(issues.ClosedState) && state != issues.ClosedState:
-			return nil
-		}
-
-		var labels []issues.Label
-		for _, l := range i.Labels {
-			labels = append(labels, issues.Label{
-				Name: l.Name,
-				// TODO: Can we use label ID to figure out its color?
-				Color: issues.RGB{R: 0xed, G: 0xed, B: 0xed}, // maintner.Corpus doesn't support GitHub issue label colors, so fall back to a default light gray.
-			})
-		}
 		replies := 0
 		err := i.ForeachComment(func(*maintner.GitHubComment) error {
 			replies++
@@ -69,27 +122,53 @@ func (s service) List(_ context.Context, rs issues.RepoSpec, opt issues.IssueLis
 		if err != nil {
 			return err
 		}
-		is = append(is, issues.Issue{
+		issue := issues.Issue{
 			ID:     uint64(i.Number),
-			State:  state,
+			State:  ghState(i),
 			Title:  i.Title,
-			Labels: labels,
+			Labels: ghLabels(i, colors),
 			Comment: issues.Comment{
 				User:      ghUser(i.User),
 				CreatedAt: i.Created,
 			},
 			Replies: replies,
-		})
+		}
+		ovs[issue.ID].applyIssue(&issue)
+		if !matchState(opt.State, issue.State) {
+			return nil
+		}
+		is = append(is, issue)
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	for _, o := range ovs {
+		if o.created == nil {
+			continue
+		}
+		issue := *o.created
+		issue.Body = ""
+		o.applyIssue(&issue)
+		if !matchState(opt.State, issue.State) {
+			continue
+		}
+		is = append(is, issue)
+	}
 	sort.Slice(is, func(i, j int) bool { return is[i].ID > is[j].ID })
 	return is, nil
 }
 
 func (s service) Count(_ context.Context, rs issues.RepoSpec, opt issues.IssueListOptions) (uint64, error) {
+	if len(opt.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return 0, &issues.InvalidArgumentError{Field: "Fields", Reason: "filtering by custom fields is not supported"}
+	}
+	if opt.Sort != "" {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return 0, &issues.InvalidArgumentError{Field: "Sort", Reason: "sorting by custom fields is not supported"}
+	}
+
 	repoID, err := ghRepoID(rs)
 	if err != nil {
 		return 0, err
@@ -101,6 +180,8 @@ func (s service) Count(_ context.Context, rs issues.RepoSpec, opt issues.IssueLi
 		return 0, fmt.Errorf("repo %v not found", rs)
 	}
 
+	ovs := s.repoOverlays(rs, repo)
+
 	var count uint64
 	err = repo.ForeachIssue(func(issue *maintner.GitHubIssue) error {
 		if issue.NotExist || issue.PullRequest {
@@ -108,10 +189,10 @@ func (s service) Count(_ context.Context, rs issues.RepoSpec, opt issues.IssueLi
 		}
 
 		state := ghState(issue)
-		switch {
-		case opt.State == issues.StateFilter(issues.OpenState) && state != issues.OpenState:
-			return nil
-		case opt.State == issues.StateFilter(issues.ClosedState) && state != issues.ClosedState:
+		if o := ovs[uint64(issue.Number)]; o != nil && o.state != nil {
+			state = *o.state
+		}
+		if !matchState(opt.State, state) {
 			return nil
 		}
 
@@ -122,35 +203,62 @@ func (s service) Count(_ context.Context, rs issues.RepoSpec, opt issues.IssueLi
 	if err != nil {
 		return 0, err
 	}
+	for _, o := range ovs {
+		if o.created == nil {
+			continue
+		}
+		state := o.created.State
+		if o.state != nil {
+			state = *o.state
+		}
+		if !matchState(opt.State, state) {
+			continue
+		}
+		count++
+	}
 
 	return count, nil
 }
 
-func (s service) Get(_ context.Context, rs issues.RepoSpec, id uint64) (issues.Issue, error) {
+func (s service) Get(ctx context.Context, rs issues.RepoSpec, id uint64) (issues.Issue, error) {
 	repoID, err := ghRepoID(rs)
 	if err != nil {
 		return issues.Issue{}, err
 	}
+	colors, err := s.labelColors(ctx, rs)
+	if err != nil {
+		return issues.Issue{}, err
+	}
 	s.c.RLock()
 	defer s.c.RUnlock()
-	repo := s.c.GitHub().Repo(repoID.Owner, repoID.Repo)
-	if repo == nil {
-		return issues.Issue{}, fmt.Errorf("repo %v not found", rs)
+	_, i, o, err := s.lookup(rs, repoID, id)
+	if err != nil {
+		return issues.Issue{}, err
 	}
-	i := repo.Issue(int32(id))
-	if i == nil || i.NotExist || i.PullRequest {
-		return issues.Issue{}, os.ErrNotExist
+	if i == nil {
+		issue := *o.created
+		o.applyIssue(&issue)
+		return issue, nil
 	}
 
-	return issues.Issue{
-		ID:    uint64(i.Number),
-		State: ghState(i),
-		Title: i.Title,
-		Comment: issues.Comment{
-			User:      ghUser(i.User),
-			CreatedAt: i.Created,
-		},
-	}, nil
+	replies := 0
+	err = i.ForeachComment(func(*maintner.GitHubComment) error {
+		replies++
+		return nil
+	})
+	if err != nil {
+		return issues.Issue{}, err
+	}
+	issue := issues.Issue{
+		ID:      uint64(i.Number),
+		State:   ghState(i),
+		Title:   i.Title,
+		Labels:  ghLabels(i, colors),
+		Comment: ghDescription(i),
+		Replies: replies,
+	}
+	o.applyIssue(&issue)
+	return issue, nil
 }
 
 func (s service) ListComments(_ context.Context, rs issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]issues.Comment, error) {
@@ -160,25 +268,124 @@ func (s service) ListComments(_ context.Context, rs issues.RepoSpec, id uint64,
 	}
 	s.c.RLock()
 	defer s.c.RUnlock()
+	_, i, o, err := s.lookup(rs, repoID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := comments(i, o)
+	start, end := page(opt, len(cs))
+	return cs[start:end], err
+}
+
+func (s service) ListEvents(ctx context.Context, rs issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]issues.Event, error) {
+	repoID, err := ghRepoID(rs)
+	if err != nil {
+		return nil, err
+	}
+	colors, err := s.labelColors(ctx, rs)
+	if err != nil {
+		return nil, err
+	}
+	s.c.RLock()
+	defer s.c.RUnlock()
+	repo, i, o, err := s.lookup(rs, repoID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := s.events(repo, i, o, colors)
+	start, end := page(opt, len(es))
+	return es[start:end], err
+}
+
+// issue returns the issue with the specified id, and its repo.
+// s.c must be locked for reading.
+func (s service) issue(rs issues.RepoSpec, repoID maintner.GitHubRepoID, id uint64) (*maintner.GitHubRepo, *maintner.GitHubIssue, error) {
 	repo := s.c.GitHub().Repo(repoID.Owner, repoID.Repo)
 	if repo == nil {
-		return nil, fmt.Errorf("repo %v not found", rs)
+		return nil, nil, fmt.Errorf("repo %v not found", rs)
 	}
 	i := repo.Issue(int32(id))
 	if i == nil || i.NotExist || i.PullRequest {
-		return nil, os.ErrNotExist
+		return nil, nil, os.ErrNotExist
 	}
+	return repo, i, nil
+}
 
-	var cs []issues.Comment
-	cs = append(cs, issues.Comment{
-		ID:        0, // We use 0 as a special ID for the comment that is the issue description.
-		User:      ghUser(i.User),
-		CreatedAt: i.Created,
-		// Can't use i.Updated for issue body because of false positives, since it includes the entire issue (e.g., if it was closed, that changes its Updated time).
-		Body:      i.Body,
-		Reactions: nil, // maintner.Corpus doesn't support GitHub issue reactions.
-	})
-	err = i.ForeachComment(func(c *maintner.GitHubComment) error {
+// lookup returns the issue with the specified id and its repo, like issue, along with
+// data written to it via the mutator that the corpus hasn't caught up with (or nil).
+// If the issue was created via the mutator and the corpus doesn't have it yet,
+// the returned repo and issue are nil, and o.created is non-nil.
+// s.c must be locked for reading.
+func (s service) lookup(rs issues.RepoSpec, repoID maintner.GitHubRepoID, id uint64) (*maintner.GitHubRepo, *maintner.GitHubIssue, *overlayIssue, error) {
+	repo, i, err := s.issue(rs, repoID, id)
+	if os.IsNotExist(err) {
+		o := s.ov.lookup(rs, id, nil)
+		if o == nil || o.created == nil {
+			return nil, nil, nil, os.ErrNotExist
+		}
+		return nil, nil, o, nil
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+	return repo, i, s.ov.lookup(rs, id, i), nil
+}
+
+// repoOverlays returns data written via the mutator to issues in repo
+// that the corpus hasn't caught up with, by issue ID.
+// s.c must be locked for reading.
+func (s service) repoOverlays(rs issues.RepoSpec, repo *maintner.GitHubRepo) map[uint64]*overlayIssue {
+	ovs := make(map[uint64]*overlayIssue)
+	for _, id := range s.ov.ids(rs) {
+		i := repo.Issue(int32(id))
+		if i != nil && (i.NotExist || i.PullRequest) {
+			i = nil
+		}
+		if o := s.ov.lookup(rs, id, i); o != nil {
+			ovs[id] = o
+		}
+	}
+	return ovs
+}
+
+// comments returns comments of issue i, or of the issue created via
+// the mutator if i is nil, with written data o applied.
+func comments(i *maintner.GitHubIssue, o *overlayIssue) ([]issues.Comment, error) {
+	if i == nil {
+		return o.applyComments([]issues.Comment{o.created.Comment}), nil
+	}
+	cs, err := ghComments(i)
+	return o.applyComments(cs), err
+}
+
+// events returns events of issue i in repo, or of the issue created via
+// the mutator if i is nil, with written data o applied.
+// s.c must be locked for reading.
+func (s service) events(repo *maintner.GitHubRepo, i *maintner.GitHubIssue, o *overlayIssue, colors map[string]issues.RGB) ([]issues.Event, error) {
+	if i == nil {
+		return o.applyEvents(nil), nil
+	}
+	es, err := s.ghEvents(repo, i, colors)
+	return o.applyEvents(es), err
+}
+
+// matchState reports whether an issue in the specified state matches filter.
+func matchState(filter issues.StateFilter, state issues.State) bool {
+	switch filter {
+	case issues.StateFilter(issues.OpenState):
+		return state == issues.OpenState
+	case issues.StateFilter(issues.ClosedState):
+		return state == issues.ClosedState
+	default:
+		return true
+	}
+}
+
+// ghComments returns comments of issue i, starting with the issue description.
+func ghComments(i *maintner.GitHubIssue) ([]issues.Comment, error) {
+	cs := []issues.Comment{ghDescription(i)}
+	err := i.ForeachComment(func(c *maintner.GitHubComment) error {
 		var edited *issues.Edited
 		if !c.Updated.Equal(c.Created) {
 			edited = &issues.Edited{
@@ -196,39 +403,27 @@ func (s service) ListComments(_ context.Context, rs issues.RepoSpec, id uint64,
 		})
 		return nil
 	})
-	if opt != nil {
-		// Pagination.
-		start := opt.Start
-		if start > len(cs) {
-			start = len(cs)
-		}
-		end := opt.Start + opt.Length
-		if end > len(cs) {
-			end = len(cs)
-		}
-		cs = cs[start:end]
-	}
 	return cs, err
 }
 
-func (s service) ListEvents(_ context.Context, rs issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]issues.Event, error) {
-	repoID, err := ghRepoID(rs)
-	if err != nil {
-		return nil, err
-	}
-	s.c.RLock()
-	defer s.c.RUnlock()
-	repo := s.c.GitHub().Repo(repoID.Owner, repoID.Repo)
-	if repo == nil {
-		return nil, fmt.Errorf("repo %v not found", rs)
-	}
-	i := repo.Issue(int32(id))
-	if i == nil || i.NotExist || i.PullRequest {
-		return nil, os.ErrNotExist
+// ghDescription returns the description of issue i.
+func ghDescription(i *maintner.GitHubIssue) issues.Comment {
+	return issues.Comment{
+		ID:        0, // We use 0 as a special ID for the comment that is the issue description.
+		User:      ghUser(i.User),
+		CreatedAt: i.Created,
+		// Can't use i.Updated for issue body because of false positives, since it includes the entire issue (e.g., if it was closed, that changes its Updated time).
+		Body:      i.Body,
+		Reactions: nil, // maintner.Corpus doesn't support GitHub issue reactions.
 	}
+}
 
+// ghEvents returns events of issue i in repo, with label colors from colors,
+// in chronological order.
+// s.c must be locked for reading.
+func (s service) ghEvents(repo *maintner.GitHubRepo, i *maintner.GitHubIssue, colors map[string]issues.RGB) ([]issues.Event, error) {
 	var es []issues.Event
-	err = i.ForeachEvent(func(e *maintner.GitHubIssueEvent) error {
+	err := i.ForeachEvent(func(e *maintner.GitHubIssueEvent) error {
 		et := issues.EventType(e.Type)
 		if !et.Valid() {
 			return nil
@@ -240,6 +435,10 @@ func (s service) ListEvents(_ context.Context, rs issues.RepoSpec, id uint64, op
 			Type:      et,
 		}
 		switch et {
+		case issues.Closed:
+			ev.Close = issues.Close{
+				Closer: s.closer(repo, i, e),
+			}
 		case issues.Renamed:
 			ev.Rename = &issues.Rename{
 				From: e.From,
@@ -248,7 +447,7 @@ func (s service) ListEvents(_ context.Context, rs issues.RepoSpec, id uint64, op
 		case issues.Labeled, issues.Unlabeled:
 			ev.Label = &issues.Label{
 				Name:  e.Label,
-				Color: issues.RGB{R: 0xED, G: 0xED, B: 0xED}, // maintner.Corpus doesn't support GitHub issue label colors, so fall back to a default light gray.
+				Color: labelColor(colors, e.Label),
 			}
 		case issues.Milestoned, issues.Demilestoned:
 			ev.Milestone = &issues.Milestone{
@@ -258,35 +457,31 @@ func (s service) ListEvents(_ context.Context, rs issues.RepoSpec, id uint64, op
 		es = append(es, ev)
 		return nil
 	})
-	if opt != nil {
-		// Pagination.
-		start := opt.Start
-		if start > len(es) {
-			start = len(es)
-		}
-		end := opt.Start + opt.Length
-		if end > len(es) {
-			end = len(es)
-		}
-		es = es[start:end]
+	if err != nil {
+		return es, err
 	}
-	return es, err
-}
-
-func (service) CreateComment(_ context.Context, rs issues.RepoSpec, id uint64, c issues.Comment) (issues.Comment, error) {
-	return issues.Comment{}, fmt.Errorf("CreateComment: not implemented")
-}
-
-func (service) Create(_ context.Context, rs issues.RepoSpec, i issues.Issue) (issues.Issue, error) {
-	return issues.Issue{}, fmt.Errorf("Create: not implemented")
-}
-
-func (service) Edit(_ context.Context, rs issues.RepoSpec, id uint64, ir issues.IssueRequest) (issues.Issue, []issues.Event, error) {
-	return issues.Issue{}, nil, fmt.Errorf("Edit: not implemented")
+	if s.opt.Gerrit {
+		es = append(es, s.gerritEvents(repo, i)...)
+		sort.SliceStable(es, func(i, j int) bool { return es[i].CreatedAt.Before(es[j].CreatedAt) })
+	}
+	return es, nil
 }
 
-func (service) EditComment(_ context.Context, rs issues.RepoSpec, id uint64, cr issues.CommentRequest) (issues.Comment, error) {
-	return issues.Comment{}, fmt.Errorf("EditComment: not implemented")
+// page returns the bounds of the page specified by opt
+// in a list of n items. A nil opt means all items.
+func page(opt *issues.ListOptions, n int) (start, end int) {
+	if opt == nil {
+		return 0, n
+	}
+	start = opt.Start
+	if start > n {
+		start = n
+	}
+	end = opt.Start + opt.Length
+	if end > n {
+		end = n
+	}
+	return start, end
 }
 
 // ghRepoID converts a RepoSpec into a maintner.GitHubRepoID.
@@ -313,6 +508,19 @@ func ghState(issue *maintner.GitHubIssue) issues.State {
 	}
 }
 
+// ghLabels returns labels of issue i, sorted by name, with colors from colors.
+func ghLabels(i *maintner.GitHubIssue, colors map[string]issues.RGB) []issues.Label {
+	var labels []issues.Label
+	for _, l := range i.Labels {
+		labels = append(labels, issues.Label{
+			Name:  l.Name,
+			Color: labelColor(colors, l.Name),
+		})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
 // ghUser converts a GitHub user into a users.User.
 func ghUser(user *maintner.GitHubUser) users.User {
 	return users.User{
@@ -0,0 +1,198 @@
+package maintner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"golang.org/x/build/maintner"
+	"golang.org/x/build/maintner/maintpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestLabelColors(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "owner/repo"}
+	corpus := newCorpus(t, &maintpb.Mutation{GithubIssue: &maintpb.GithubIssueMutation{
+		Owner:    "owner",
+		Repo:     "repo",
+		Number:   1,
+		Id:       1001,
+		User:     gopher,
+		Created:  at(0),
+		Updated:  at(0),
+		Title:    "Title",
+		AddLabel: []*maintpb.GithubLabel{{Id: 2, Name: "docs"}, {Id: 1, Name: "bug"}},
+		Event: []*maintpb.GithubIssueEvent{
+			{Id: 3001, EventType: "labeled", ActorId: gopher.Id, Created: at(1), Label: &maintpb.GithubLabel{Name: "bug"}},
+		},
+	}})
+	red := issues.RGB{R: 0xff}
+
+	for _, tc := range []struct {
+		name   string
+		colors LabelColorSource
+		bug    issues.RGB
+	}{
+		{"default", nil, defaultLabelColor},
+		{"static", StaticLabelColors{"bug": red}, red},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewService(corpus, &Options{LabelColors: tc.colors})
+			issue, err := s.Get(ctx, repo, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []issues.Label{{Name: "bug", Color: tc.bug}, {Name: "docs", Color: defaultLabelColor}}
+			if !reflect.DeepEqual(issue.Labels, want) {
+				t.Errorf("got labels %+v, want %+v", issue.Labels, want)
+			}
+			es, err := s.ListEvents(ctx, repo, 1, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(es) != 1 || es[0].Label == nil || es[0].Label.Color != tc.bug {
+				t.Errorf("got events %+v, want a labeled event with color %v", es, tc.bug)
+			}
+		})
+	}
+}
+
+func TestListTimeline(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "owner/repo"}
+	s := NewService(newCorpus(t, &maintpb.Mutation{GithubIssue: &maintpb.GithubIssueMutation{
+		Owner:   "owner",
+		Repo:    "repo",
+		Number:  1,
+		Id:      1001,
+		User:    gopher,
+		Created: at(0),
+		Updated: at(0),
+		Title:   "New title",
+		Body:    "Body",
+		Closed:  &maintpb.BoolChange{Val: true},
+		Comment: []*maintpb.GithubIssueCommentMutation{
+			{Id: 2001, User: reviewer, Body: "First", Created: at(2), Updated: at(2)},
+			{Id: 2002, User: gopher, Body: "Second", Created: at(4), Updated: at(4)},
+		},
+		Event: []*maintpb.GithubIssueEvent{
+			{Id: 3002, EventType: "closed", ActorId: gopher.Id, Created: at(4)},
+			{Id: 3001, EventType: "renamed", ActorId: gopher.Id, Created: at(1), RenameFrom: "Title", RenameTo: "New title"},
+		},
+	}}), nil)
+	tl := s.(issues.TimelineLister)
+
+	// The description comes first, then comments and events by time,
+	// with comments before events at the same time.
+	items, err := tl.ListTimeline(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"comment 0", "event renamed", "comment 2001", "comment 2002", "event closed"}
+	if got := describeTimeline(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("got timeline %q, want %q", got, want)
+	}
+
+	// Pages are of the merged timeline.
+	for _, tc := range []struct {
+		opt  issues.ListOptions
+		want []string
+	}{
+		{issues.ListOptions{Start: 0, Length: 2}, want[0:2]},
+		{issues.ListOptions{Start: 2, Length: 2}, want[2:4]},
+		{issues.ListOptions{Start: 4, Length: 2}, want[4:]},
+		{issues.ListOptions{Start: 6, Length: 2}, nil},
+	} {
+		opt := tc.opt
+		items, err := tl.ListTimeline(ctx, repo, 1, &opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := describeTimeline(items); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("page %+v: got %q, want %q", tc.opt, got, tc.want)
+		}
+	}
+
+	// Get includes the description.
+	issue, err := s.Get(ctx, repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Body != "Body" || issue.Replies != 2 || issue.State != issues.ClosedState {
+		t.Errorf("got issue %+v, want a closed issue with body and 2 replies", issue)
+	}
+}
+
+func TestCustomFieldsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "owner/repo"}
+	s := NewService(newCorpus(t), nil)
+
+	for _, tc := range []struct {
+		opt   issues.IssueListOptions
+		field string
+	}{
+		{issues.IssueListOptions{State: issues.AllStates, Fields: []issues.Field{{Name: "Priority", Value: 1}}}, "Fields"},
+		{issues.IssueListOptions{State: issues.AllStates, Sort: "Priority"}, "Sort"},
+	} {
+		_, err := s.List(ctx, repo, tc.opt)
+		if e, ok := err.(*issues.InvalidArgumentError); !ok || e.Field != tc.field {
+			t.Errorf("List with %+v: got error %v, want *issues.InvalidArgumentError for %s", tc.opt, err, tc.field)
+		}
+		_, err = s.Count(ctx, repo, tc.opt)
+		if e, ok := err.(*issues.InvalidArgumentError); !ok || e.Field != tc.field {
+			t.Errorf("Count with %+v: got error %v, want *issues.InvalidArgumentError for %s", tc.opt, err, tc.field)
+		}
+	}
+}
+
+// describeTimeline returns a description of each timeline item.
+func describeTimeline(items []interface{}) []string {
+	var ds []string
+	for _, item := range items {
+		switch item := item.(type) {
+		case issues.Comment:
+			ds = append(ds, fmt.Sprint("comment ", item.ID))
+		case issues.Event:
+			ds = append(ds, fmt.Sprint("event ", item.Type))
+		}
+	}
+	return ds
+}
+
+var (
+	gopher   = &maintpb.GithubUser{Id: 1, Login: "gopher"}
+	reviewer = &maintpb.GithubUser{Id: 2, Login: "reviewer"}
+)
+
+// at returns a time min minutes after a fixed base time.
+func at(min int) *timestamppb.Timestamp {
+	return timestamppb.New(time.Date(2018, 1, 1, 0, min, 0, 0, time.UTC))
+}
+
+// newCorpus returns a corpus initialized with mutations ms.
+func newCorpus(t *testing.T, ms ...*maintpb.Mutation) *maintner.Corpus {
+	t.Helper()
+	corpus := new(maintner.Corpus)
+	err := corpus.Initialize(context.Background(), mutationSource(ms))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return corpus
+}
+
+// mutationSource is a maintner.MutationSource of a fixed list of mutations.
+type mutationSource []*maintpb.Mutation
+
+func (ms mutationSource) GetMutations(ctx context.Context) <-chan maintner.MutationStreamEvent {
+	ch := make(chan maintner.MutationStreamEvent, len(ms)+1)
+	for _, m := range ms {
+		ch <- maintner.MutationStreamEvent{Mutation: m}
+	}
+	ch <- maintner.MutationStreamEvent{End: true}
+	return ch
+}
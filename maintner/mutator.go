@@ -0,0 +1,95 @@
+package maintner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shurcooL/issues"
+)
+
+// Create implements issues.Service by delegating to Options.Mutator.
+func (s service) Create(ctx context.Context, rs issues.RepoSpec, i issues.Issue) (issues.Issue, error) {
+	if s.opt.Mutator == nil {
+		return issues.Issue{}, fmt.Errorf("Create: not implemented")
+	}
+	issue, err := s.opt.Mutator.Create(ctx, s.mutatorRepo(rs), i)
+	if err != nil {
+		return issues.Issue{}, err
+	}
+	if issue.Body == "" {
+		// Not all services return the body of a created issue.
+		issue.Body = i.Body
+	}
+	s.ov.write(rs, issue.ID, func(o *overlayIssue) {
+		created := issue
+		o.created = &created
+	})
+	return issue, nil
+}
+
+// CreateComment implements issues.Service by delegating to Options.Mutator.
+func (s service) CreateComment(ctx context.Context, rs issues.RepoSpec, id uint64, c issues.Comment) (issues.Comment, error) {
+	if s.opt.Mutator == nil {
+		return issues.Comment{}, fmt.Errorf("CreateComment: not implemented")
+	}
+	comment, err := s.opt.Mutator.CreateComment(ctx, s.mutatorRepo(rs), id, c)
+	if err != nil {
+		return issues.Comment{}, err
+	}
+	s.ov.write(rs, id, func(o *overlayIssue) {
+		o.comments = append(o.comments, comment)
+	})
+	return comment, nil
+}
+
+// Edit implements issues.Service by delegating to Options.Mutator.
+func (s service) Edit(ctx context.Context, rs issues.RepoSpec, id uint64, ir issues.IssueRequest) (issues.Issue, []issues.Event, error) {
+	if s.opt.Mutator == nil {
+		return issues.Issue{}, nil, fmt.Errorf("Edit: not implemented")
+	}
+	issue, events, err := s.opt.Mutator.Edit(ctx, s.mutatorRepo(rs), id, ir)
+	if err != nil {
+		return issues.Issue{}, nil, err
+	}
+	s.ov.write(rs, id, func(o *overlayIssue) {
+		if ir.State != nil {
+			state := issue.State
+			o.state = &state
+		}
+		if ir.Title != nil {
+			title := issue.Title
+			o.title = &title
+		}
+		if ir.Labels != nil {
+			labels := issue.Labels
+			o.labels = &labels
+		}
+		o.events = append(o.events, events...)
+	})
+	return issue, events, nil
+}
+
+// EditComment implements issues.Service by delegating to Options.Mutator.
+func (s service) EditComment(ctx context.Context, rs issues.RepoSpec, id uint64, cr issues.CommentRequest) (issues.Comment, error) {
+	if s.opt.Mutator == nil {
+		return issues.Comment{}, fmt.Errorf("EditComment: not implemented")
+	}
+	comment, err := s.opt.Mutator.EditComment(ctx, s.mutatorRepo(rs), id, cr)
+	if err != nil {
+		return issues.Comment{}, err
+	}
+	if cr.Body != nil {
+		s.ov.write(rs, id, func(o *overlayIssue) {
+			o.bodies[cr.ID] = comment.Body
+		})
+	}
+	return comment, nil
+}
+
+// mutatorRepo maps repo to the corresponding repo of Options.Mutator.
+func (s service) mutatorRepo(repo issues.RepoSpec) issues.RepoSpec {
+	if s.opt.MutatorRepo == nil {
+		return repo
+	}
+	return s.opt.MutatorRepo(repo)
+}
@@ -0,0 +1,230 @@
+package maintner
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"golang.org/x/build/maintner"
+)
+
+// overlayTTL is how long written data is overlaid on reads at most,
+// in case the corpus never catches up with it in a recognizable way
+// (for example, because the data was changed again elsewhere).
+const overlayTTL = 10 * time.Minute
+
+// overlay holds data written via Options.Mutator that the corpus
+// may not have caught up with yet. A nil *overlay holds nothing.
+type overlay struct {
+	mu     sync.Mutex
+	issues map[overlayKey]*overlayIssue
+}
+
+type overlayKey struct {
+	repo issues.RepoSpec
+	id   uint64
+}
+
+// overlayIssue is data written to a single issue.
+type overlayIssue struct {
+	at       time.Time         // At is the time of the latest write.
+	created  *issues.Issue     // Created is the issue, if it was created via the mutator.
+	state    *issues.State     // State is the state set by an edit, if any.
+	title    *string           // Title is the title set by an edit, if any.
+	labels   *[]issues.Label   // Labels are the labels set by an edit, if any.
+	comments []issues.Comment  // Comments are created comments.
+	bodies   map[uint64]string // Bodies are edited comment bodies, by comment ID.
+	events   []issues.Event    // Events are events created by edits.
+}
+
+// write records data written to the specified issue via f.
+func (ov *overlay) write(repo issues.RepoSpec, id uint64, f func(o *overlayIssue)) {
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+	k := overlayKey{repo: repo, id: id}
+	o, ok := ov.issues[k]
+	if !ok {
+		o = &overlayIssue{bodies: make(map[uint64]string)}
+		ov.issues[k] = o
+	}
+	o.at = time.Now()
+	f(o)
+}
+
+// lookup returns a copy of data written to the specified issue that corpus issue i
+// hasn't caught up with, or nil if there's none. i is nil if the corpus doesn't have
+// the issue. Data the corpus has caught up with is dropped.
+// The corpus must be locked for reading.
+func (ov *overlay) lookup(repo issues.RepoSpec, id uint64, i *maintner.GitHubIssue) *overlayIssue {
+	if ov == nil {
+		return nil
+	}
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+	k := overlayKey{repo: repo, id: id}
+	o, ok := ov.issues[k]
+	if !ok {
+		return nil
+	}
+	if o.reconcile(i, time.Now()) {
+		delete(ov.issues, k)
+		return nil
+	}
+	c := *o
+	c.comments = append([]issues.Comment(nil), o.comments...)
+	c.events = append([]issues.Event(nil), o.events...)
+	c.bodies = make(map[uint64]string, len(o.bodies))
+	for id, body := range o.bodies {
+		c.bodies[id] = body
+	}
+	return &c
+}
+
+// ids returns IDs of issues in repo that have written data.
+func (ov *overlay) ids(repo issues.RepoSpec) []uint64 {
+	if ov == nil {
+		return nil
+	}
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+	var ids []uint64
+	for k := range ov.issues {
+		if k.repo == repo {
+			ids = append(ids, k.id)
+		}
+	}
+	return ids
+}
+
+// reconcile drops data that corpus issue i (nil if the corpus doesn't have the issue)
+// has caught up with, and reports whether no data remains.
+func (o *overlayIssue) reconcile(i *maintner.GitHubIssue, now time.Time) (done bool) {
+	if now.Sub(o.at) > overlayTTL {
+		return true
+	}
+	if i == nil {
+		return false
+	}
+	o.created = nil
+	if o.state != nil && ghState(i) == *o.state {
+		o.state = nil
+	}
+	if o.title != nil && i.Title == *o.title {
+		o.title = nil
+	}
+	if o.labels != nil && sameLabels(i, *o.labels) {
+		o.labels = nil
+	}
+	bodies := map[uint64]string{0: i.Body}
+	i.ForeachComment(func(c *maintner.GitHubComment) error {
+		bodies[uint64(c.ID)] = c.Body
+		return nil
+	})
+	var comments []issues.Comment
+	for _, c := range o.comments {
+		if _, ok := bodies[c.ID]; !ok {
+			comments = append(comments, c)
+		}
+	}
+	o.comments = comments
+	for id, body := range o.bodies {
+		if b, ok := bodies[id]; ok && b == body {
+			delete(o.bodies, id)
+		}
+	}
+	var corpusEvents []*maintner.GitHubIssueEvent
+	i.ForeachEvent(func(e *maintner.GitHubIssueEvent) error {
+		corpusEvents = append(corpusEvents, e)
+		return nil
+	})
+	var events []issues.Event
+	for _, e := range o.events {
+		if !matchEvent(&corpusEvents, e) {
+			events = append(events, e)
+		}
+	}
+	o.events = events
+	return o.state == nil && o.title == nil && o.labels == nil && len(o.comments) == 0 && len(o.bodies) == 0 && len(o.events) == 0
+}
+
+// eventSlack is how far apart in time an event written via the mutator
+// and a corpus event can be, and still be considered the same event.
+// The mutator may only predict the time of events it creates.
+const eventSlack = time.Minute
+
+// matchEvent reports whether one of corpus events es is event e, and if so,
+// removes it from es, so that it isn't matched again. Events are matched by ID,
+// or by type, actor and time, since the mutator doesn't always know IDs of corpus
+// events (for example, githubapi only knows GraphQL node IDs of some events).
+func matchEvent(es *[]*maintner.GitHubIssueEvent, e issues.Event) bool {
+	for j, ce := range *es {
+		same := uint64(ce.ID) == e.ID ||
+			issues.EventType(ce.Type) == e.Type &&
+				ce.Actor != nil && uint64(ce.Actor.ID) == e.Actor.ID &&
+				absDuration(ce.Created.Sub(e.CreatedAt)) <= eventSlack
+		if same {
+			*es = append((*es)[:j:j], (*es)[j+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// sameLabels reports whether corpus issue i has exactly labels.
+func sameLabels(i *maintner.GitHubIssue, labels []issues.Label) bool {
+	if len(i.Labels) != len(labels) {
+		return false
+	}
+	for _, l := range labels {
+		if !i.HasLabel(l.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyIssue applies written data to issue.
+func (o *overlayIssue) applyIssue(issue *issues.Issue) {
+	if o == nil {
+		return
+	}
+	if o.state != nil {
+		issue.State = *o.state
+	}
+	if o.title != nil {
+		issue.Title = *o.title
+	}
+	if o.labels != nil {
+		issue.Labels = *o.labels
+	}
+	if body, ok := o.bodies[0]; ok {
+		issue.Body = body
+	}
+	issue.Replies += len(o.comments)
+}
+
+// applyComments applies written data to comments cs, and returns the result.
+func (o *overlayIssue) applyComments(cs []issues.Comment) []issues.Comment {
+	if o == nil {
+		return cs
+	}
+	cs = append(cs, o.comments...)
+	for i, c := range cs {
+		if body, ok := o.bodies[c.ID]; ok {
+			cs[i].Body = body
+		}
+	}
+	return cs
+}
+
+// applyEvents applies written data to events es, and returns the result
+// in chronological order.
+func (o *overlayIssue) applyEvents(es []issues.Event) []issues.Event {
+	if o == nil || len(o.events) == 0 {
+		return es
+	}
+	es = append(es, o.events...)
+	sort.SliceStable(es, func(i, j int) bool { return es[i].CreatedAt.Before(es[j].CreatedAt) })
+	return es
+}
@@ -0,0 +1,161 @@
+package maintner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+	"golang.org/x/build/maintner/maintpb"
+)
+
+func TestOverlay(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "golang/go"}
+	closed := issues.Event{
+		ID:        0xfeedface, // Not a corpus event ID, like githubapi events that only have node IDs.
+		Actor:     users.User{UserSpec: users.UserSpec{ID: uint64(gopher.Id), Domain: "github.com"}},
+		CreatedAt: at(10).AsTime().Add(2 * time.Second),
+		Type:      issues.Closed,
+	}
+	m := &mockMutator{events: []issues.Event{closed}, comment: issues.Comment{ID: 2001, Body: "Done."}}
+	s := NewService(newCorpus(t, githubIssue(1, "Issue 1", true)), &Options{Mutator: m}).(service)
+
+	// Written data is overlaid until the corpus catches up.
+	state := issues.ClosedState
+	if _, _, err := s.Edit(ctx, repo, 1, issues.IssueRequest{State: &state}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateComment(ctx, repo, 1, issues.Comment{Body: "Done."}); err != nil {
+		t.Fatal(err)
+	}
+	checkIssue(t, s, repo, issues.ClosedState, 1, []issues.EventType{issues.Closed})
+
+	// The corpus catches up. Its closed event has a different ID and a slightly
+	// different time, but it's the same event, so it's shown once.
+	s.c = newCorpus(t, func() *maintpb.Mutation {
+		m := githubIssue(1, "Issue 1", false, &maintpb.GithubIssueEvent{Id: 3001, EventType: "closed", ActorId: gopher.Id, Created: at(10)})
+		m.GithubIssue.Comment = []*maintpb.GithubIssueCommentMutation{{Id: 2001, User: gopher, Body: "Done.", Created: at(10), Updated: at(10)}}
+		return m
+	}())
+	checkIssue(t, s, repo, issues.ClosedState, 1, []issues.EventType{issues.Closed})
+	if ids := s.ov.ids(repo); len(ids) != 0 {
+		t.Errorf("got overlaid issues %v after the corpus caught up, want none", ids)
+	}
+}
+
+func TestOverlayReconcile(t *testing.T) {
+	corpus := newCorpus(t, githubIssue(1, "Title", false,
+		&maintpb.GithubIssueEvent{Id: 3001, EventType: "closed", ActorId: reviewer.Id, Created: at(10)},
+		&maintpb.GithubIssueEvent{Id: 3002, EventType: "renamed", ActorId: gopher.Id, Created: at(10), RenameFrom: "Old", RenameTo: "Title"},
+	))
+	i := corpus.GitHub().Repo("golang", "go").Issue(1)
+	gopherUser := users.User{UserSpec: users.UserSpec{ID: uint64(gopher.Id), Domain: "github.com"}}
+	event := func(id uint64, typ issues.EventType, min int) issues.Event {
+		return issues.Event{ID: id, Actor: gopherUser, CreatedAt: at(min).AsTime(), Type: typ}
+	}
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name   string
+		o      overlayIssue
+		events []issues.Event // Events that remain.
+		done   bool
+	}{
+		{
+			name: "caught up",
+			o:    overlayIssue{at: now, title: strPtr("Title"), events: []issues.Event{event(1, issues.Renamed, 10)}},
+			done: true,
+		},
+		{
+			name:   "same ID",
+			o:      overlayIssue{at: now, events: []issues.Event{event(3001, issues.Closed, 20)}},
+			events: nil,
+			done:   true,
+		},
+		{
+			name:   "different actor",
+			o:      overlayIssue{at: now, events: []issues.Event{event(1, issues.Closed, 10)}},
+			events: []issues.Event{event(1, issues.Closed, 10)},
+		},
+		{
+			name:   "different time",
+			o:      overlayIssue{at: now, events: []issues.Event{event(1, issues.Renamed, 15)}},
+			events: []issues.Event{event(1, issues.Renamed, 15)},
+		},
+		{
+			name:   "matched once",
+			o:      overlayIssue{at: now, events: []issues.Event{event(1, issues.Renamed, 10), event(2, issues.Renamed, 10)}},
+			events: []issues.Event{event(2, issues.Renamed, 10)},
+		},
+		{
+			name:   "not caught up",
+			o:      overlayIssue{at: now, title: strPtr("New title")},
+			events: nil,
+		},
+		{
+			name: "expired",
+			o:    overlayIssue{at: now.Add(-overlayTTL - time.Second), title: strPtr("New title")},
+			done: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := tc.o
+			done := o.reconcile(i, now)
+			if done != tc.done {
+				t.Errorf("got done %v, want %v", done, tc.done)
+			}
+			if !done && !reflect.DeepEqual(o.events, tc.events) {
+				t.Errorf("got remaining events %+v, want %+v", o.events, tc.events)
+			}
+		})
+	}
+}
+
+// checkIssue checks the state, replies and event types of issue 1 in repo.
+func checkIssue(t *testing.T, s issues.Service, repo issues.RepoSpec, state issues.State, replies int, types []issues.EventType) {
+	t.Helper()
+	issue, err := s.Get(context.Background(), repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.State != state || issue.Replies != replies {
+		t.Errorf("got state %q and %d replies, want %q and %d", issue.State, issue.Replies, state, replies)
+	}
+	es, err := s.ListEvents(context.Background(), repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []issues.EventType
+	for _, e := range es {
+		got = append(got, e.Type)
+	}
+	if !reflect.DeepEqual(got, types) {
+		t.Errorf("got events %v, want %v", got, types)
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+// mockMutator is an issues.Service that only supports Edit and CreateComment.
+// Edit returns events, and CreateComment returns comment.
+type mockMutator struct {
+	issues.Service
+
+	events  []issues.Event
+	comment issues.Comment
+}
+
+func (m *mockMutator) Edit(_ context.Context, _ issues.RepoSpec, id uint64, ir issues.IssueRequest) (issues.Issue, []issues.Event, error) {
+	issue := issues.Issue{ID: id, State: issues.OpenState}
+	if ir.State != nil {
+		issue.State = *ir.State
+	}
+	return issue, m.events, nil
+}
+
+func (m *mockMutator) CreateComment(context.Context, issues.RepoSpec, uint64, issues.Comment) (issues.Comment, error) {
+	return m.comment, nil
+}
@@ -0,0 +1,67 @@
+package maintner
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shurcooL/issues"
+)
+
+// IsTimelineLister implements issues.TimelineLister.
+func (service) IsTimelineLister(issues.RepoSpec) bool { return true }
+
+// ListTimeline implements issues.TimelineLister.
+// Pagination applies to the merged timeline of comments and events.
+func (s service) ListTimeline(ctx context.Context, rs issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]interface{}, error) {
+	repoID, err := ghRepoID(rs)
+	if err != nil {
+		return nil, err
+	}
+	colors, err := s.labelColors(ctx, rs)
+	if err != nil {
+		return nil, err
+	}
+	s.c.RLock()
+	defer s.c.RUnlock()
+	repo, i, o, err := s.lookup(rs, repoID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := comments(i, o)
+	if err != nil {
+		return nil, err
+	}
+	es, err := s.events(repo, i, o, colors)
+	if err != nil {
+		return nil, err
+	}
+	var timeline []interface{}
+	for _, c := range cs {
+		timeline = append(timeline, c)
+	}
+	for _, e := range es {
+		timeline = append(timeline, e)
+	}
+	// The issue description comes first, and items at the same time keep
+	// their relative order (comments before events), so sort stably.
+	sort.SliceStable(timeline[1:], func(i, j int) bool {
+		return createdAt(timeline[1+i]).Before(createdAt(timeline[1+j]))
+	})
+
+	start, end := page(opt, len(timeline))
+	return timeline[start:end], nil
+}
+
+// createdAt returns the creation time of timeline item t.
+func createdAt(t interface{}) time.Time {
+	switch t := t.(type) {
+	case issues.Comment:
+		return t.CreatedAt
+	case issues.Event:
+		return t.CreatedAt
+	default:
+		panic("unreachable")
+	}
+}
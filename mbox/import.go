@@ -0,0 +1,547 @@
+package mbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// Import reads an mbox file from r, and returns a read-only issues.Service
+// that serves the message threads it contains as issues of repo.
+// Each thread becomes an issue, with its first message as the issue description.
+// The returned service can be passed to issues.CopierFrom.CopyFrom
+// to bootstrap an issue tracker from a mailing list archive.
+//
+// Issue, comment and event IDs are preserved for messages written by Export.
+// Other messages are given the next available IDs, in chronological order.
+//
+// user maps message senders to users. If nil, senders with addresses
+// written by Export are mapped back to their original users.UserSpec,
+// and other senders are given a zero users.UserSpec.
+func Import(r io.Reader, repo issues.RepoSpec, user func(*mail.Address) users.User) (issues.Service, error) {
+	if user == nil {
+		user = defaultUser
+	}
+	raws, err := readMbox(r)
+	if err != nil {
+		return nil, err
+	}
+	var ms []*parsedMessage
+	for _, raw := range raws {
+		m, err := parseMessage(raw, user)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].Date.Before(ms[j].Date) })
+	return &service{
+		repo:    repo,
+		threads: threads(repo, ms),
+	}, nil
+}
+
+// readMbox splits an mbox file into raw messages, undoing mboxrd quoting.
+// A "From " line starts a new message only at the beginning
+// of the file or after a blank line.
+func readMbox(r io.Reader) ([][]byte, error) {
+	var (
+		msgs      [][]byte
+		cur       *bytes.Buffer
+		prevBlank = true
+	)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			switch {
+			case prevBlank && strings.HasPrefix(line, "From "):
+				if cur != nil {
+					msgs = append(msgs, trimSeparator(cur.Bytes()))
+				}
+				cur = new(bytes.Buffer)
+			case cur != nil:
+				if quotedFromLine.MatchString(line) {
+					line = line[1:]
+				}
+				cur.WriteString(line)
+			}
+			prevBlank = line == "\n" || line == "\r\n"
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if cur != nil {
+		msgs = append(msgs, trimSeparator(cur.Bytes()))
+	}
+	return msgs, nil
+}
+
+// quotedFromLine matches lines that were quoted in mboxrd format.
+var quotedFromLine = regexp.MustCompile(`^>+From `)
+
+// trimSeparator trims the blank line that separates messages in an mbox file.
+func trimSeparator(b []byte) []byte {
+	if bytes.HasSuffix(b, []byte("\n\n")) {
+		return b[:len(b)-1]
+	}
+	return b
+}
+
+// parsedMessage is a message read from an mbox file.
+type parsedMessage struct {
+	ID      string // Without angle brackets.
+	Parent  string // Without angle brackets. Empty if not a reply.
+	From    users.User
+	Date    time.Time
+	Subject string
+	Header  mail.Header
+	Body    string
+}
+
+func parseMessage(raw []byte, user func(*mail.Address) users.User) (*parsedMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, fmt.Errorf("message %s has no valid From header: %v", msg.Header.Get("Message-ID"), err)
+	}
+	date, err := msg.Header.Date()
+	if err != nil {
+		return nil, fmt.Errorf("message %s has no valid Date header: %v", msg.Header.Get("Message-ID"), err)
+	}
+	body, err := TextBody(msg)
+	if err != nil {
+		return nil, fmt.Errorf("message %s: %v", msg.Header.Get("Message-ID"), err)
+	}
+	m := &parsedMessage{
+		ID:      trimAngle(msg.Header.Get("Message-ID")),
+		From:    user(from[0]),
+		Date:    date,
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+		Header:  msg.Header,
+		Body:    strings.TrimRight(body, "\n"),
+	}
+	if irt := msg.Header.Get("In-Reply-To"); irt != "" {
+		m.Parent = trimAngle(strings.Fields(irt)[0])
+	} else if refs := strings.Fields(msg.Header.Get("References")); len(refs) > 0 {
+		m.Parent = trimAngle(refs[len(refs)-1])
+	}
+	return m, nil
+}
+
+// defaultUser maps addresses written by Export back to their users.UserSpec.
+func defaultUser(addr *mail.Address) users.User {
+	u := users.User{
+		Login: addr.Name,
+		Email: addr.Address,
+	}
+	if i := strings.LastIndex(addr.Address, "@"); i != -1 {
+		if id, err := strconv.ParseUint(addr.Address[:i], 10, 64); err == nil {
+			u.UserSpec = users.UserSpec{ID: id, Domain: addr.Address[i+1:]}
+			u.Email = ""
+		}
+	}
+	if u.Login == "" {
+		u.Login = addr.Address
+	}
+	return u
+}
+
+// thread is a single issue with its comments and events.
+type thread struct {
+	Issue    issues.Issue
+	Comments []issues.Comment // Sorted by ID, issue description first.
+	Events   []issues.Event   // Sorted by ID.
+}
+
+// threads groups messages ms, sorted chronologically, into issue threads sorted by issue ID.
+func threads(repo issues.RepoSpec, ms []*parsedMessage) []*thread {
+	byID := make(map[string]*parsedMessage)
+	for _, m := range ms {
+		if m.ID != "" {
+			byID[m.ID] = m
+		}
+	}
+	root := func(m *parsedMessage) *parsedMessage {
+		seen := map[*parsedMessage]bool{m: true}
+		for {
+			p, ok := byID[m.Parent]
+			if !ok || seen[p] {
+				return m
+			}
+			seen[p] = true
+			m = p
+		}
+	}
+
+	// Group messages by their root message, preserving chronological order.
+	var roots []*parsedMessage
+	replies := make(map[*parsedMessage][]*parsedMessage)
+	for _, m := range ms {
+		r := root(m)
+		if r == m {
+			roots = append(roots, m)
+			continue
+		}
+		replies[r] = append(replies[r], m)
+	}
+
+	// Assign issue IDs, preserving ones written by Export.
+	issueIDs := newIDs()
+	for _, r := range roots {
+		if mid, ok := parseMessageID(r.ID); ok && mid.Repo == repo && mid.Kind == "comment" && mid.ItemID == 0 {
+			issueIDs.reserve(r, mid.IssueID)
+		}
+	}
+
+	var ts []*thread
+	for _, r := range roots {
+		issueID := issueIDs.get(r)
+		t := &thread{
+			Issue: issues.Issue{
+				ID:    issueID,
+				State: issues.State(r.Header.Get("X-Issue-State")),
+				Title: strings.TrimSpace(stripReplyPrefix(r.Subject)),
+			},
+		}
+		for _, l := range r.Header["X-Issue-Label"] {
+			t.Issue.Labels = append(t.Issue.Labels, parseLabel(decodeHeader(l)))
+		}
+
+		commentIDs, eventIDs := newIDs(), newIDs()
+		commentIDs.reserve(r, 0)
+		for _, m := range replies[r] {
+			mid, ok := parseMessageID(m.ID)
+			if !ok || mid.Repo != repo || mid.IssueID != issueID {
+				continue
+			}
+			switch {
+			case mid.Kind == "comment" && m.Header.Get("X-Issue-Event") == "":
+				commentIDs.reserve(m, mid.ItemID)
+			case mid.Kind == "event" && m.Header.Get("X-Issue-Event") != "":
+				eventIDs.reserve(m, mid.ItemID)
+			}
+		}
+
+		t.Comments = append(t.Comments, issues.Comment{
+			ID:        0,
+			User:      r.From,
+			CreatedAt: r.Date,
+			Body:      r.Body,
+		})
+		derivedState := issues.OpenState
+		for _, m := range replies[r] {
+			et := issues.EventType(m.Header.Get("X-Issue-Event"))
+			if et == "" {
+				t.Comments = append(t.Comments, issues.Comment{
+					ID:        commentIDs.get(m),
+					User:      m.From,
+					CreatedAt: m.Date,
+					Body:      m.Body,
+				})
+				continue
+			}
+			if !et.Valid() {
+				continue
+			}
+			e := issues.Event{
+				ID:        eventIDs.get(m),
+				Actor:     m.From,
+				CreatedAt: m.Date,
+				Type:      et,
+			}
+			switch et {
+			case issues.Reopened:
+				derivedState = issues.OpenState
+			case issues.Closed:
+				derivedState = issues.ClosedState
+			case issues.Renamed:
+				e.Rename = &issues.Rename{
+					From: decodeHeader(m.Header.Get("X-Issue-Rename-From")),
+					To:   decodeHeader(m.Header.Get("X-Issue-Rename-To")),
+				}
+			case issues.Labeled, issues.Unlabeled:
+				l := parseLabel(decodeHeader(m.Header.Get("X-Issue-Label")))
+				e.Label = &l
+			case issues.Milestoned, issues.Demilestoned:
+				e.Milestone = &issues.Milestone{Name: decodeHeader(m.Header.Get("X-Issue-Milestone"))}
+			case issues.Related, issues.Unrelated:
+				if r, ok := parseRelation(decodeHeader(m.Header.Get("X-Issue-Relation"))); ok {
+					e.Relation = &r
+				}
+			case issues.FieldChanged:
+				if name := decodeHeader(m.Header.Get("X-Issue-Field")); name != "" {
+					e.FieldChange = &issues.FieldChange{Name: name}
+					if v := m.Header.Get("X-Issue-Field-From"); v != "" {
+						e.FieldChange.From = parseTypedFieldValue(decodeHeader(v))
+					}
+					if v := m.Header.Get("X-Issue-Field-To"); v != "" {
+						e.FieldChange.To = parseTypedFieldValue(decodeHeader(v))
+					}
+				}
+			}
+			t.Events = append(t.Events, e)
+		}
+		if t.Issue.State != issues.OpenState && t.Issue.State != issues.ClosedState {
+			t.Issue.State = derivedState
+		}
+		sort.Slice(t.Comments, func(i, j int) bool { return t.Comments[i].ID < t.Comments[j].ID })
+		sort.Slice(t.Events, func(i, j int) bool { return t.Events[i].ID < t.Events[j].ID })
+		t.Issue.Comment = t.Comments[0]
+		t.Issue.Replies = len(t.Comments) - 1
+		ts = append(ts, t)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Issue.ID < ts[j].Issue.ID })
+	return ts
+}
+
+// ids assigns unique IDs to messages. IDs reserved for specific messages
+// are kept, and other messages get the next available ID starting from 1.
+type ids struct {
+	assigned map[*parsedMessage]uint64
+	used     map[uint64]bool
+	next     uint64
+}
+
+func newIDs() *ids {
+	return &ids{
+		assigned: make(map[*parsedMessage]uint64),
+		used:     make(map[uint64]bool),
+		next:     1,
+	}
+}
+
+// reserve assigns id to m, unless id is already taken.
+func (ids *ids) reserve(m *parsedMessage, id uint64) {
+	if ids.used[id] {
+		return
+	}
+	ids.assigned[m] = id
+	ids.used[id] = true
+}
+
+// get returns the ID of m, assigning the next available one if needed.
+func (ids *ids) get(m *parsedMessage) uint64 {
+	if id, ok := ids.assigned[m]; ok {
+		return id
+	}
+	for ids.used[ids.next] {
+		ids.next++
+	}
+	id := ids.next
+	ids.assigned[m] = id
+	ids.used[id] = true
+	return id
+}
+
+var replyPrefix = regexp.MustCompile(`(?i)^\s*((re|fwd?):\s*)+`)
+
+// stripReplyPrefix strips "Re:" and "Fwd:" prefixes from a subject.
+func stripReplyPrefix(subject string) string {
+	return replyPrefix.ReplaceAllString(subject, "")
+}
+
+func trimAngle(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+func decodeHeader(v string) string {
+	s, err := new(mime.WordDecoder).DecodeHeader(v)
+	if err != nil {
+		return v
+	}
+	return s
+}
+
+// TextBody returns the text/plain body of m, decoding its
+// Content-Transfer-Encoding. For multipart messages, the first
+// text/plain part is used. Non-UTF-8 charsets other than ISO-8859-1
+// are returned as is.
+func TextBody(m *mail.Message) (string, error) {
+	return textBody(textproto.MIMEHeader(m.Header), m.Body)
+}
+
+var errNoText = errors.New("message has no text/plain part")
+
+func textBody(h textproto.MIMEHeader, r io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		// Default to plain text, as specified in RFC 2045.
+		mediaType, params = "text/plain", nil
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return "", errNoText
+			} else if err != nil {
+				return "", err
+			}
+			body, err := textBody(p.Header, p)
+			if err == errNoText {
+				continue
+			} else if err != nil {
+				return "", err
+			}
+			return body, nil
+		}
+	case mediaType == "text/plain":
+		switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+		case "quoted-printable":
+			r = quotedprintable.NewReader(r)
+		case "base64":
+			r = base64.NewDecoder(base64.StdEncoding, r)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(params["charset"]) {
+		case "iso-8859-1", "latin1":
+			rs := make([]rune, len(b))
+			for i, c := range b {
+				rs[i] = rune(c)
+			}
+			return strings.ReplaceAll(string(rs), "\r\n", "\n"), nil
+		default:
+			return strings.ReplaceAll(string(b), "\r\n", "\n"), nil
+		}
+	default:
+		return "", errNoText
+	}
+}
+
+// service is a read-only issues.Service backed by imported threads.
+type service struct {
+	repo    issues.RepoSpec
+	threads []*thread // Sorted by issue ID.
+}
+
+func (s *service) thread(rs issues.RepoSpec, id uint64) (*thread, error) {
+	if rs != s.repo {
+		return nil, fmt.Errorf("repo %v not found", rs)
+	}
+	i := sort.Search(len(s.threads), func(i int) bool { return s.threads[i].Issue.ID >= id })
+	if i == len(s.threads) || s.threads[i].Issue.ID != id {
+		return nil, os.ErrNotExist
+	}
+	return s.threads[i], nil
+}
+
+func (s *service) List(_ context.Context, rs issues.RepoSpec, opt issues.IssueListOptions) ([]issues.Issue, error) {
+	if rs != s.repo {
+		return nil, fmt.Errorf("repo %v not found", rs)
+	}
+	if len(opt.Fields) > 0 {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, &issues.InvalidArgumentError{Field: "Fields", Reason: "filtering by custom fields is not supported"}
+	}
+	if opt.Sort != "" {
+		// TODO: Map to 400 Bad Request HTTP error.
+		return nil, &issues.InvalidArgumentError{Field: "Sort", Reason: "sorting by custom fields is not supported"}
+	}
+	var is []issues.Issue
+	for i := len(s.threads); i > 0; i-- {
+		issue := s.threads[i-1].Issue
+		if opt.State != issues.AllStates && issue.State != issues.State(opt.State) {
+			continue
+		}
+		is = append(is, issue)
+	}
+	return is, nil
+}
+
+func (s *service) Count(ctx context.Context, rs issues.RepoSpec, opt issues.IssueListOptions) (uint64, error) {
+	is, err := s.List(ctx, rs, opt)
+	return uint64(len(is)), err
+}
+
+func (s *service) Get(_ context.Context, rs issues.RepoSpec, id uint64) (issues.Issue, error) {
+	t, err := s.thread(rs, id)
+	if err != nil {
+		return issues.Issue{}, err
+	}
+	return t.Issue, nil
+}
+
+func (s *service) ListComments(_ context.Context, rs issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]issues.Comment, error) {
+	t, err := s.thread(rs, id)
+	if err != nil {
+		return nil, err
+	}
+	cs := t.Comments
+	if opt != nil {
+		start, end := paginate(len(cs), opt)
+		cs = cs[start:end]
+	}
+	return cs, nil
+}
+
+func (s *service) ListEvents(_ context.Context, rs issues.RepoSpec, id uint64, opt *issues.ListOptions) ([]issues.Event, error) {
+	t, err := s.thread(rs, id)
+	if err != nil {
+		return nil, err
+	}
+	es := t.Events
+	if opt != nil {
+		start, end := paginate(len(es), opt)
+		es = es[start:end]
+	}
+	return es, nil
+}
+
+func (*service) CreateComment(_ context.Context, rs issues.RepoSpec, id uint64, c issues.Comment) (issues.Comment, error) {
+	return issues.Comment{}, fmt.Errorf("CreateComment: not implemented")
+}
+
+func (*service) Create(_ context.Context, rs issues.RepoSpec, i issues.Issue) (issues.Issue, error) {
+	return issues.Issue{}, fmt.Errorf("Create: not implemented")
+}
+
+func (*service) Edit(_ context.Context, rs issues.RepoSpec, id uint64, ir issues.IssueRequest) (issues.Issue, []issues.Event, error) {
+	return issues.Issue{}, nil, fmt.Errorf("Edit: not implemented")
+}
+
+func (*service) EditComment(_ context.Context, rs issues.RepoSpec, id uint64, cr issues.CommentRequest) (issues.Comment, error) {
+	return issues.Comment{}, fmt.Errorf("EditComment: not implemented")
+}
+
+// paginate returns the start and end indices of a page of n elements.
+func paginate(n int, opt *issues.ListOptions) (start, end int) {
+	start = opt.Start
+	if start > n {
+		start = n
+	}
+	end = opt.Start + opt.Length
+	if end > n {
+		end = n
+	}
+	return start, end
+}
@@ -0,0 +1,446 @@
+// Package mbox implements exporting issue threads from an issues.Service
+// into an mbox file, and importing them back.
+//
+// Each timeline item of an issue (its description, comments and events)
+// becomes an RFC 5322 message. Replies are threaded to the issue description
+// via In-Reply-To and References headers, so that mail clients and list
+// archives display one thread per issue.
+package mbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/issues"
+	"github.com/shurcooL/users"
+)
+
+// Export writes all issues of repo in src to w, in mbox format.
+// Issues are written in increasing order of their IDs.
+func Export(ctx context.Context, w io.Writer, src issues.Service, repo issues.RepoSpec) error {
+	is, err := src.List(ctx, repo, issues.IssueListOptions{State: issues.AllStates})
+	if err != nil {
+		return err
+	}
+	sort.Slice(is, func(i, j int) bool { return is[i].ID < is[j].ID })
+	bw := bufio.NewWriter(w)
+	for _, i := range is {
+		i, err = src.Get(ctx, repo, i.ID) // Needed to get the state and title at the time of export.
+		if err != nil {
+			return err
+		}
+		timeline, err := listTimeline(ctx, src, repo, i.ID)
+		if err != nil {
+			return err
+		}
+		err = writeThread(bw, repo, i, timeline)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// listTimeline lists the timeline items (issues.Comment, issues.Event)
+// of the specified issue in chronological order, issue description first.
+func listTimeline(ctx context.Context, src issues.Service, repo issues.RepoSpec, id uint64) ([]interface{}, error) {
+	if tl, ok := src.(issues.TimelineLister); ok && tl.IsTimelineLister(repo) {
+		return tl.ListTimeline(ctx, repo, id, nil)
+	}
+	cs, err := src.ListComments(ctx, repo, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	es, err := src.ListEvents(ctx, repo, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	var timeline []interface{}
+	for _, c := range cs {
+		timeline = append(timeline, c)
+	}
+	for _, e := range es {
+		timeline = append(timeline, e)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return createdAt(timeline[i]).Before(createdAt(timeline[j]))
+	})
+	return timeline, nil
+}
+
+func createdAt(item interface{}) time.Time {
+	switch item := item.(type) {
+	case issues.Comment:
+		return item.CreatedAt
+	case issues.Event:
+		return item.CreatedAt
+	default:
+		panic(fmt.Errorf("unexpected timeline item type %T", item))
+	}
+}
+
+// writeThread writes a thread of messages for issue i with the given timeline.
+func writeThread(w *bufio.Writer, repo issues.RepoSpec, i issues.Issue, timeline []interface{}) error {
+	root := CommentMessageID(repo, i.ID, 0)
+	for idx, item := range timeline {
+		var m message
+		switch item := item.(type) {
+		case issues.Comment:
+			m = message{
+				From:      item.User,
+				Date:      item.CreatedAt,
+				MessageID: CommentMessageID(repo, i.ID, item.ID),
+				Body:      item.Body,
+			}
+			if item.ID == 0 {
+				m.Subject = i.Title
+				m.Header = [][2]string{{"X-Issue-State", string(i.State)}}
+				for _, l := range i.Labels {
+					m.Header = append(m.Header, [2]string{"X-Issue-Label", formatLabel(l)})
+				}
+			} else {
+				m.Subject = "Re: " + i.Title
+				m.InReplyTo = root
+			}
+		case issues.Event:
+			id := EventMessageID(repo, i.ID, item.ID)
+			if item.ID == 0 {
+				// The backend didn't provide an event ID, so fall back to
+				// the position of the event in the timeline to keep message IDs unique.
+				id = fmt.Sprintf("issue-%d.item-%d@%s", i.ID, idx, repo.URI)
+			}
+			m = message{
+				From:      item.Actor,
+				Date:      item.CreatedAt,
+				Subject:   "Re: " + i.Title,
+				MessageID: id,
+				InReplyTo: root,
+				Header:    [][2]string{{"X-Issue-Event", string(item.Type)}},
+				Body:      eventBody(item),
+			}
+			switch {
+			case item.Rename != nil:
+				m.Header = append(m.Header,
+					[2]string{"X-Issue-Rename-From", item.Rename.From},
+					[2]string{"X-Issue-Rename-To", item.Rename.To})
+			case item.Label != nil:
+				m.Header = append(m.Header, [2]string{"X-Issue-Label", formatLabel(*item.Label)})
+			case item.Milestone != nil:
+				m.Header = append(m.Header, [2]string{"X-Issue-Milestone", item.Milestone.Name})
+			case item.Relation != nil:
+				m.Header = append(m.Header, [2]string{"X-Issue-Relation", formatRelation(*item.Relation)})
+			case item.FieldChange != nil:
+				m.Header = append(m.Header, [2]string{"X-Issue-Field", item.FieldChange.Name})
+				if item.FieldChange.From != nil {
+					m.Header = append(m.Header, [2]string{"X-Issue-Field-From", formatTypedFieldValue(item.FieldChange.From)})
+				}
+				if item.FieldChange.To != nil {
+					m.Header = append(m.Header, [2]string{"X-Issue-Field-To", formatTypedFieldValue(item.FieldChange.To)})
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected timeline item type %T", item)
+		}
+		err := m.WriteTo(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatFieldValue formats the value of a custom field.
+func formatFieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format("2006-01-02")
+	case users.UserSpec:
+		return fmt.Sprintf("%d@%s", v.ID, v.Domain)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatTypedFieldValue formats the value of a custom field as "type value",
+// where type is the issues.FieldType of its values.
+func formatTypedFieldValue(v interface{}) string {
+	var typ issues.FieldType
+	switch v.(type) {
+	case int64:
+		typ = issues.IntField
+	case time.Time:
+		typ = issues.DateField
+	case users.UserSpec:
+		typ = issues.UserField
+	default:
+		typ = issues.StringField
+	}
+	return string(typ) + " " + formatFieldValue(v)
+}
+
+// parseTypedFieldValue parses a value formatted by formatTypedFieldValue.
+// It returns nil if s isn't a valid value.
+func parseTypedFieldValue(s string) interface{} {
+	i := strings.Index(s, " ")
+	if i == -1 {
+		return nil
+	}
+	typ, v := issues.FieldType(s[:i]), s[i+1:]
+	switch typ {
+	case issues.StringField:
+		return v
+	case issues.IntField:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil
+		}
+		return n
+	case issues.DateField:
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil
+		}
+		return t
+	case issues.UserField:
+		i := strings.LastIndex(v, "@")
+		if i == -1 {
+			return nil
+		}
+		id, err := strconv.ParseUint(v[:i], 10, 64)
+		if err != nil {
+			return nil
+		}
+		return users.UserSpec{ID: id, Domain: v[i+1:]}
+	default:
+		return nil
+	}
+}
+
+// eventBody returns a human-readable description of event e.
+func eventBody(e issues.Event) string {
+	actor := e.Actor.Login
+	switch e.Type {
+	case issues.Reopened:
+		return fmt.Sprintf("%s reopened this issue.\n", actor)
+	case issues.Closed:
+		switch c := e.Close.Closer.(type) {
+		case issues.Change:
+			return fmt.Sprintf("%s closed this issue in %s.\n", actor, c.HTMLURL)
+		case issues.Commit:
+			return fmt.Sprintf("%s closed this issue in commit %s.\n", actor, c.SHA)
+		case issues.IssueRef:
+			return fmt.Sprintf("%s closed this issue as a duplicate of %s#%d.\n", actor, c.Repo.URI, c.ID)
+		default:
+			return fmt.Sprintf("%s closed this issue.\n", actor)
+		}
+	case issues.Renamed:
+		return fmt.Sprintf("%s changed the title from %q to %q.\n", actor, e.Rename.From, e.Rename.To)
+	case issues.Labeled:
+		return fmt.Sprintf("%s added the %s label.\n", actor, e.Label.Name)
+	case issues.Unlabeled:
+		return fmt.Sprintf("%s removed the %s label.\n", actor, e.Label.Name)
+	case issues.Milestoned:
+		return fmt.Sprintf("%s added this to the %s milestone.\n", actor, e.Milestone.Name)
+	case issues.Demilestoned:
+		return fmt.Sprintf("%s removed this from the %s milestone.\n", actor, e.Milestone.Name)
+	case issues.CommentDeleted:
+		return fmt.Sprintf("%s deleted a comment.\n", actor)
+	case issues.Related:
+		if e.Relation == nil {
+			return fmt.Sprintf("%s related this issue to another.\n", actor)
+		}
+		return fmt.Sprintf("%s marked this issue as %s %s#%d.\n", actor, strings.Replace(string(e.Relation.Type), "_", " ", -1), e.Relation.Repo.URI, e.Relation.ID)
+	case issues.Unrelated:
+		if e.Relation == nil {
+			return fmt.Sprintf("%s removed a relation.\n", actor)
+		}
+		return fmt.Sprintf("%s removed the %s %s#%d relation.\n", actor, strings.Replace(string(e.Relation.Type), "_", " ", -1), e.Relation.Repo.URI, e.Relation.ID)
+	case issues.FieldChanged:
+		c := e.FieldChange
+		switch {
+		case c == nil:
+			return fmt.Sprintf("%s changed a field.\n", actor)
+		case c.From == nil:
+			return fmt.Sprintf("%s set %s to %s.\n", actor, c.Name, formatFieldValue(c.To))
+		case c.To == nil:
+			return fmt.Sprintf("%s unset %s (was %s).\n", actor, c.Name, formatFieldValue(c.From))
+		default:
+			return fmt.Sprintf("%s changed %s from %s to %s.\n", actor, c.Name, formatFieldValue(c.From), formatFieldValue(c.To))
+		}
+	case issues.CrossReferenced:
+		var src interface{}
+		if e.CrossReference != nil {
+			src = e.CrossReference.Source
+		}
+		switch c := src.(type) {
+		case issues.IssueRef:
+			return fmt.Sprintf("%s referenced this issue from %s#%d (%s).\n", actor, c.Repo.URI, c.ID, c.HTMLURL)
+		case issues.Change:
+			return fmt.Sprintf("%s referenced this issue in %s.\n", actor, c.HTMLURL)
+		case issues.Commit:
+			return fmt.Sprintf("%s referenced this issue in commit %s.\n", actor, c.SHA)
+		default:
+			return fmt.Sprintf("%s referenced this issue.\n", actor)
+		}
+	default:
+		return fmt.Sprintf("%s: %s.\n", actor, e.Type)
+	}
+}
+
+// formatLabel formats l as "name #rrggbb".
+func formatLabel(l issues.Label) string {
+	return l.Name + " " + l.Color.HexString()
+}
+
+// parseLabel parses a label formatted by formatLabel.
+func parseLabel(s string) issues.Label {
+	i := strings.LastIndex(s, " #")
+	if i == -1 {
+		return issues.Label{Name: s}
+	}
+	var c issues.RGB
+	_, err := fmt.Sscanf(s[i+2:], "%02x%02x%02x", &c.R, &c.G, &c.B)
+	if err != nil {
+		return issues.Label{Name: s}
+	}
+	return issues.Label{Name: s[:i], Color: c}
+}
+
+// formatRelation formats r as "type repo#id".
+func formatRelation(r issues.Relation) string {
+	return fmt.Sprintf("%s %s#%d", r.Type, r.Repo.URI, r.ID)
+}
+
+// parseRelation parses a relation formatted by formatRelation.
+// It reports whether s was a valid relation.
+func parseRelation(s string) (issues.Relation, bool) {
+	i, j := strings.Index(s, " "), strings.LastIndex(s, "#")
+	if i == -1 || j < i {
+		return issues.Relation{}, false
+	}
+	id, err := strconv.ParseUint(s[j+1:], 10, 64)
+	if err != nil {
+		return issues.Relation{}, false
+	}
+	r := issues.Relation{Type: issues.RelationType(s[:i]), Repo: issues.RepoSpec{URI: s[i+1 : j]}, ID: id}
+	return r, r.Validate() == nil
+}
+
+// message is an RFC 5322 message in an mbox file.
+type message struct {
+	From      users.User
+	Date      time.Time
+	Subject   string
+	MessageID string // Without angle brackets.
+	InReplyTo string // Without angle brackets. Empty if not a reply.
+	Header    [][2]string
+	Body      string
+}
+
+// WriteTo writes the message to w, including its leading "From " line.
+// The body is quoted as described by the mboxrd format.
+func (m message) WriteTo(w *bufio.Writer) error {
+	from := address(m.From)
+	fmt.Fprintf(w, "From %s %s\n", from.Address, m.Date.UTC().Format(time.ANSIC))
+	fmt.Fprintf(w, "From: %s\n", from.String())
+	fmt.Fprintf(w, "Date: %s\n", m.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(w, "Subject: %s\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(w, "Message-ID: <%s>\n", m.MessageID)
+	if m.InReplyTo != "" {
+		fmt.Fprintf(w, "In-Reply-To: <%s>\n", m.InReplyTo)
+		fmt.Fprintf(w, "References: <%s>\n", m.InReplyTo)
+	}
+	for _, kv := range m.Header {
+		fmt.Fprintf(w, "%s: %s\n", kv[0], mime.QEncoding.Encode("utf-8", kv[1]))
+	}
+	fmt.Fprint(w, "MIME-Version: 1.0\n")
+	fmt.Fprint(w, "Content-Type: text/plain; charset=utf-8\n")
+	fmt.Fprint(w, "Content-Transfer-Encoding: 8bit\n")
+	fmt.Fprint(w, "\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if fromLine.MatchString(line) {
+			w.WriteString(">")
+		}
+		w.WriteString(line)
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
+
+// fromLine matches lines that need to be quoted in mboxrd format.
+var fromLine = regexp.MustCompile(`^>*From `)
+
+// address returns the email address of user u.
+// Users without a public email address get an address of the form "{{.ID}}@{{.Domain}}",
+// which is recognized by the importer and mapped back to the same user.
+func address(u users.User) *mail.Address {
+	addr := u.Email
+	if addr == "" {
+		addr = fmt.Sprintf("%d@%s", u.ID, u.Domain)
+	}
+	return &mail.Address{Name: u.Login, Address: addr}
+}
+
+// CommentMessageID returns the Message-ID, without angle brackets,
+// of the specified issue comment. The issue description is comment 0.
+func CommentMessageID(repo issues.RepoSpec, issueID, commentID uint64) string {
+	return fmt.Sprintf("issue-%d.comment-%d@%s", issueID, commentID, repo.URI)
+}
+
+// EventMessageID returns the Message-ID, without angle brackets,
+// of the specified issue event.
+func EventMessageID(repo issues.RepoSpec, issueID, eventID uint64) string {
+	return fmt.Sprintf("issue-%d.event-%d@%s", issueID, eventID, repo.URI)
+}
+
+// ParseMessageID parses a Message-ID created by CommentMessageID or EventMessageID.
+// Surrounding angle brackets, if any, are ignored. It reports whether id was recognized.
+func ParseMessageID(id string) (repo issues.RepoSpec, issueID uint64, ok bool) {
+	mid, ok := parseMessageID(id)
+	return mid.Repo, mid.IssueID, ok
+}
+
+// messageID is a parsed Message-ID.
+type messageID struct {
+	Repo    issues.RepoSpec
+	IssueID uint64
+	Kind    string // "comment", "event", "item".
+	ItemID  uint64
+}
+
+var messageIDRE = regexp.MustCompile(`^issue-(\d+)\.(comment|event|item)-(\d+)@(.+)$`)
+
+func parseMessageID(id string) (messageID, bool) {
+	id = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+	m := messageIDRE.FindStringSubmatch(id)
+	if m == nil {
+		return messageID{}, false
+	}
+	issueID, err := strconv.ParseUint(m[1], 10, 64)
+	if err != nil {
+		return messageID{}, false
+	}
+	itemID, err := strconv.ParseUint(m[3], 10, 64)
+	if err != nil {
+		return messageID{}, false
+	}
+	return messageID{
+		Repo:    issues.RepoSpec{URI: m[4]},
+		IssueID: issueID,
+		Kind:    m[2],
+		ItemID:  itemID,
+	}, true
+}
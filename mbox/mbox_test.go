@@ -0,0 +1,237 @@
+package mbox
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/issues"
+)
+
+const archive = `From alice@example.com Mon Jan  2 15:04:05 2017
+From: Alice <alice@example.com>
+Date: Mon, 02 Jan 2017 15:04:05 +0000
+Subject: Crash on startup
+Message-ID: <abc@example.com>
+
+It crashes.
+>From the logs, it's a nil pointer.
+
+From 2@example.org Mon Jan  2 16:00:00 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:00 +0000
+Subject: Re: Crash on startup
+Message-ID: <def@example.com>
+In-Reply-To: <abc@example.com>
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Fixed, thanks! =E2=9C=93
+
+From 2@example.org Mon Jan  2 16:00:01 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:01 +0000
+Subject: Re: Crash on startup
+Message-ID: <ghi@example.com>
+In-Reply-To: <abc@example.com>
+X-Issue-Event: closed
+
+bob closed this issue.
+
+From 2@example.org Mon Jan  2 16:00:02 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:02 +0000
+Subject: Re: Crash on startup
+Message-ID: <mno@example.com>
+In-Reply-To: <abc@example.com>
+X-Issue-Event: related
+X-Issue-Relation: blocks example.com/repo#2
+
+bob marked this issue as blocks example.com/repo#2.
+
+From 2@example.org Mon Jan  2 16:00:03 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:03 +0000
+Subject: Re: Crash on startup
+Message-ID: <pqr@example.com>
+In-Reply-To: <abc@example.com>
+X-Issue-Event: unrelated
+
+bob removed a relation.
+
+From 2@example.org Mon Jan  2 16:00:04 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:04 +0000
+Subject: Re: Crash on startup
+Message-ID: <stu@example.com>
+In-Reply-To: <abc@example.com>
+X-Issue-Event: field_changed
+X-Issue-Field: Priority
+X-Issue-Field-From: int 2
+X-Issue-Field-To: int 1
+
+bob changed Priority from 2 to 1.
+
+From 2@example.org Mon Jan  2 16:00:05 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:05 +0000
+Subject: Re: Crash on startup
+Message-ID: <vwx@example.com>
+In-Reply-To: <abc@example.com>
+X-Issue-Event: field_changed
+X-Issue-Field: Due
+X-Issue-Field-To: date 2017-01-31
+
+bob set Due to 2017-01-31.
+
+From 2@example.org Mon Jan  2 16:00:06 2017
+From: bob <2@example.org>
+Date: Mon, 02 Jan 2017 16:00:06 +0000
+Subject: Re: Crash on startup
+Message-ID: <yz@example.com>
+In-Reply-To: <abc@example.com>
+X-Issue-Event: field_changed
+
+bob changed a field.
+
+From carol@example.com Tue Jan  3 10:00:00 2017
+From: Carol <carol@example.com>
+Date: Tue, 03 Jan 2017 10:00:00 +0000
+Subject: Feature request
+Message-ID: <jkl@example.com>
+
+Please add a feature.
+
+`
+
+func TestImportExport(t *testing.T) {
+	ctx := context.Background()
+	repo := issues.RepoSpec{URI: "example.com/repo"}
+
+	src, err := Import(strings.NewReader(archive), repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is, err := src.List(ctx, repo, issues.IssueListOptions{State: issues.AllStates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(is), 2; got != want {
+		t.Fatalf("got %d issues, want %d", got, want)
+	}
+	crash, err := src.Get(ctx, repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := crash.State, issues.ClosedState; got != want {
+		t.Errorf("got state %q, want %q", got, want)
+	}
+	if got, want := crash.Body, "It crashes.\nFrom the logs, it's a nil pointer."; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+	comments, err := src.ListComments(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(comments), 2; got != want {
+		t.Fatalf("got %d comments, want %d", got, want)
+	}
+	if got, want := comments[1].Body, "Fixed, thanks! ✓"; got != want {
+		t.Errorf("got comment body %q, want %q", got, want)
+	}
+	if got, want := comments[1].User.UserSpec.ID, uint64(2); got != want {
+		t.Errorf("got comment author ID %d, want %d", got, want)
+	}
+	events, err := src.ListEvents(ctx, repo, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(events), 6; got != want {
+		t.Fatalf("got %d events, want %d", got, want)
+	}
+	if got, want := events[1].Relation, (&issues.Relation{Type: issues.Blocks, Repo: repo, ID: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got relation %+v, want %+v", got, want)
+	}
+	if got := events[2].Relation; got != nil {
+		t.Errorf("got relation %+v without a relation header, want nil", got)
+	}
+	for i, want := range []*issues.FieldChange{
+		{Name: "Priority", From: int64(2), To: int64(1)},
+		{Name: "Due", To: time.Date(2017, 1, 31, 0, 0, 0, 0, time.UTC)},
+		nil,
+	} {
+		if got := events[3+i].FieldChange; !reflect.DeepEqual(got, want) {
+			t.Errorf("event %d: got field change %+v, want %+v", events[3+i].ID, got, want)
+		}
+	}
+
+	// Exporting and importing again should preserve everything, including IDs.
+	var buf bytes.Buffer
+	err = Export(ctx, &buf, src, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\n>From the logs") {
+		t.Errorf("exported body is not mboxrd-quoted:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "In-Reply-To: <"+CommentMessageID(repo, 1, 0)+">") {
+		t.Errorf("exported replies are not threaded:\n%s", buf.String())
+	}
+	dst, err := Import(&buf, repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dst.(*service).threads, src.(*service).threads; !equalThreads(got, want) {
+		t.Errorf("round trip mismatch:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func equalThreads(a, b []*thread) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i].Issue.Labels, b[i].Issue.Labels) ||
+			a[i].Issue.ID != b[i].Issue.ID ||
+			a[i].Issue.State != b[i].Issue.State ||
+			a[i].Issue.Title != b[i].Issue.Title ||
+			len(a[i].Comments) != len(b[i].Comments) ||
+			len(a[i].Events) != len(b[i].Events) {
+			return false
+		}
+		for j := range a[i].Comments {
+			ca, cb := a[i].Comments[j], b[i].Comments[j]
+			if ca.ID != cb.ID || ca.Body != cb.Body || ca.User.UserSpec != cb.User.UserSpec || !ca.CreatedAt.Equal(cb.CreatedAt) {
+				return false
+			}
+		}
+		for j := range a[i].Events {
+			ea, eb := a[i].Events[j], b[i].Events[j]
+			if ea.ID != eb.ID || ea.Type != eb.Type || !ea.CreatedAt.Equal(eb.CreatedAt) ||
+				!reflect.DeepEqual(ea.Relation, eb.Relation) || !reflect.DeepEqual(ea.FieldChange, eb.FieldChange) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestParseMessageID(t *testing.T) {
+	repo := issues.RepoSpec{URI: "github.com/shurcooL/issues"}
+	for _, id := range []string{
+		CommentMessageID(repo, 12, 0),
+		"<" + CommentMessageID(repo, 12, 3) + ">",
+		EventMessageID(repo, 12, 5),
+	} {
+		gotRepo, gotID, ok := ParseMessageID(id)
+		if !ok || gotRepo != repo || gotID != 12 {
+			t.Errorf("ParseMessageID(%q) = %v, %v, %v; want %v, 12, true", id, gotRepo, gotID, ok, repo)
+		}
+	}
+	if _, _, ok := ParseMessageID("<abc@example.com>"); ok {
+		t.Error("ParseMessageID(<abc@example.com>) reported ok")
+	}
+}
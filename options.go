@@ -3,6 +3,19 @@ package issues
 // IssueListOptions are options for list operations.
 type IssueListOptions struct {
 	State StateFilter
+
+	// Fields, if not empty, filters issues to those whose custom fields have the specified values.
+	// A nil Value matches issues where the field is unset. Filters on fields that
+	// aren't defined, or with values that aren't valid for them, are rejected with
+	// an *InvalidArgumentError.
+	Fields []Field
+
+	// Sort, if not empty, is the name of a custom field to sort issues by, in ascending order.
+	// Issues where the field is unset come last. Ties, and all issues when Sort is empty,
+	// are ordered newest first.
+	Sort string
+	// SortDescending reverses the order of set values when Sort is not empty.
+	SortDescending bool
 }
 
 // StateFilter is a filter by state.
@@ -0,0 +1,90 @@
+package issues
+
+import (
+	"context"
+	"errors"
+)
+
+// Relater is an optional interface that allows linking issues to one another
+// with typed relations, like "blocks" or "duplicate of".
+//
+// A relation is recorded on both of the issues it links, with the inverse type
+// on the target issue. A Related or Unrelated event is created on each end.
+type Relater interface {
+	// AddRelation adds relation r from the specified issue id to the issue r refers to.
+	// Blocks and ParentOf relations (and their inverses) must not form cycles.
+	// Adding a DuplicateOf relation also closes the issue, with the target issue as the closer.
+	AddRelation(ctx context.Context, repo RepoSpec, id uint64, r Relation) ([]Event, error)
+	// RemoveRelation removes relation r from the specified issue id, and its inverse from the target issue.
+	RemoveRelation(ctx context.Context, repo RepoSpec, id uint64, r Relation) ([]Event, error)
+	// ListRelations lists relations of the specified issue id.
+	ListRelations(ctx context.Context, repo RepoSpec, id uint64) ([]Relation, error)
+}
+
+// Relation is a typed link from one issue to another.
+type Relation struct {
+	Type RelationType
+	Repo RepoSpec // Repo of the target issue.
+	ID   uint64   // ID of the target issue.
+}
+
+// Validate returns non-nil error if the relation is invalid.
+func (r Relation) Validate() error {
+	if !r.Type.Valid() {
+		return errors.New("invalid relation type")
+	}
+	if r.Repo.URI == "" || r.ID == 0 {
+		return errors.New("relation target must be specified")
+	}
+	return nil
+}
+
+// RelationType is the type of a relation between issues.
+type RelationType string
+
+const (
+	// DuplicateOf is when an issue is a duplicate of the target issue.
+	DuplicateOf RelationType = "duplicate_of"
+	// DuplicatedBy is the inverse of DuplicateOf.
+	DuplicatedBy RelationType = "duplicated_by"
+	// Blocks is when an issue blocks the target issue.
+	Blocks RelationType = "blocks"
+	// BlockedBy is the inverse of Blocks.
+	BlockedBy RelationType = "blocked_by"
+	// RelatesTo is when an issue relates to the target issue. It's its own inverse.
+	RelatesTo RelationType = "relates_to"
+	// ParentOf is when an issue is the parent of the target issue, such as an epic.
+	ParentOf RelationType = "parent_of"
+	// ChildOf is the inverse of ParentOf.
+	ChildOf RelationType = "child_of"
+)
+
+// Valid reports whether the relation type is valid.
+func (rt RelationType) Valid() bool {
+	switch rt {
+	case DuplicateOf, DuplicatedBy, Blocks, BlockedBy, RelatesTo, ParentOf, ChildOf:
+		return true
+	default:
+		return false
+	}
+}
+
+// Inverse returns the type of the relation as seen from its target.
+func (rt RelationType) Inverse() RelationType {
+	switch rt {
+	case DuplicateOf:
+		return DuplicatedBy
+	case DuplicatedBy:
+		return DuplicateOf
+	case Blocks:
+		return BlockedBy
+	case BlockedBy:
+		return Blocks
+	case ParentOf:
+		return ChildOf
+	case ChildOf:
+		return ParentOf
+	default:
+		return rt
+	}
+}
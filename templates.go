@@ -0,0 +1,99 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TemplateLister is an optional interface that lists issue templates of a repo,
+// and creates issues from them.
+//
+// Templates are only enforced by CreateFromTemplate. Issues created with
+// Service.Create aren't associated with a template, so fields that templates
+// require aren't enforced for them.
+type TemplateLister interface {
+	// ListTemplates lists issue templates of the specified repo.
+	ListTemplates(ctx context.Context, repo RepoSpec) ([]Template, error)
+
+	// CreateFromTemplate creates an issue from the template with the specified name.
+	// The template's defaults are applied to issue, and it's rejected with
+	// an *InvalidArgumentError if fields that the template requires aren't set.
+	CreateFromTemplate(ctx context.Context, repo RepoSpec, template string, issue Issue) (Issue, error)
+}
+
+// Template is an issue template.
+type Template struct {
+	Name        string // Name identifies the template within a repo.
+	About       string // About describes when to use the template.
+	TitlePrefix string // TitlePrefix is prepended to titles of new issues, unless already there.
+	Body        string // Body is the skeleton of the issue description.
+	Labels      []Label
+	Fields      []TemplateField
+}
+
+// TemplateField is a custom field that an issue created from a template should set.
+// Name is the name of a field defined in the repo. See FieldLister.
+type TemplateField struct {
+	Name     string
+	Required bool
+}
+
+// Apply applies defaults of template t to issue i, and returns the result.
+// The title prefix is added, the body skeleton is used if i has no body,
+// and default labels that i doesn't have are added.
+func (t Template) Apply(i Issue) Issue {
+	if t.TitlePrefix != "" && !strings.HasPrefix(i.Title, t.TitlePrefix) {
+		i.Title = t.TitlePrefix + i.Title
+	}
+	if strings.TrimSpace(i.Body) == "" {
+		i.Body = t.Body
+	}
+	labels := append([]Label(nil), i.Labels...)
+Labels:
+	for _, l := range t.Labels {
+		for _, have := range i.Labels {
+			if have.Name == l.Name {
+				continue Labels
+			}
+		}
+		labels = append(labels, l)
+	}
+	i.Labels = labels
+	return i
+}
+
+// ValidateIssue returns non-nil error if issue i is invalid,
+// or if it doesn't set all fields required by template t.
+// Missing fields are reported with an *InvalidArgumentError.
+func (t Template) ValidateIssue(i Issue) error {
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	values := FieldValues(i.Fields)
+	var missing []string
+	for _, f := range t.Fields {
+		if _, ok := values[f.Name]; f.Required && !ok {
+			missing = append(missing, strconv.Quote(f.Name))
+		}
+	}
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return &InvalidArgumentError{Field: "Fields", Reason: fmt.Sprintf("required field %s of template %q is missing", missing[0], t.Name)}
+	default:
+		return &InvalidArgumentError{Field: "Fields", Reason: fmt.Sprintf("required fields %s of template %q are missing", strings.Join(missing, ", "), t.Name)}
+	}
+}
+
+// InvalidArgumentError is returned when a request has an invalid argument.
+type InvalidArgumentError struct {
+	Field  string // Field is the name of the invalid field of the request.
+	Reason string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}